@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/shared-drawboard/internal/service"
+)
+
+// runCommand runs one of the archive subcommands instead of the server
+func runCommand(name string, args []string) error {
+	switch name {
+	case "export":
+		return exportCommand(args)
+	case "import":
+		return importCommand(args)
+	default:
+		return fmt.Errorf("unknown command %q, expected export or import", name)
+	}
+}
+
+// exportCommand writes the archive of a board to a file or stdout
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	boardID := flags.String("board", "", "id of the board to export")
+	out := flags.String("out", "", "archive file to write, stdout when empty")
+	snapshotOnly := flags.Bool("snapshot", false, "export only the current objects instead of the event log")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *boardID == "" {
+		return fmt.Errorf("export: -board is required")
+	}
+
+	s, err := service.New()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	archive, err := s.ArchiveBoard(ctx, *boardID, *snapshotOnly)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(archive)
+}
+
+// importCommand creates a new board from an archive file or stdin
+func importCommand(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	ownerID := flags.String("owner", "", "email of the user who will own the board")
+	in := flags.String("in", "", "archive file to read, stdin when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *ownerID == "" {
+		return fmt.Errorf("import: -owner is required")
+	}
+
+	r := io.Reader(os.Stdin)
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	archive, err := service.ParseArchive(data)
+	if err != nil {
+		return err
+	}
+
+	s, err := service.New()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	board, err := s.ImportBoard(ctx, *ownerID, archive)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(board.ID)
+	return nil
+}
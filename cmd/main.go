@@ -11,13 +11,22 @@ import (
 )
 
 func main() {
-	logger.Info("Server is starting...")
-
 	if err := godotenv.Load(); err != nil {
 		logger.Error("Error loading environment variables: %s", err)
 		os.Exit(1)
 	}
 
+	// drawboard export|import ... manages board archives and exits
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			logger.Error("%s", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger.Info("Server is starting...")
+
 	PORT := os.Getenv("PORT")
 	if PORT == "" {
 		logger.Error("PORT is not set")
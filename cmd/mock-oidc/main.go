@@ -0,0 +1,189 @@
+// Command mock-oidc runs an OpenID Connect identity provider for local
+// development. It signs everyone in as the same user without asking, so it
+// is kept out of the server binary.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shared-drawboard/pkg/logger"
+)
+
+// mockCode is an authorization code the mock issuer handed out and what it was issued for
+type mockCode struct {
+	clientID    string
+	redirectURI string
+	nonce       string
+	challenge   string
+	expiresAt   time.Time
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		logger.Error("%s", err)
+		os.Exit(1)
+	}
+}
+
+// run serves the identity provider until it fails
+func run(args []string) error {
+	flags := flag.NewFlagSet("mock-oidc", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:9000", "address to listen on")
+	issuer := flags.String("issuer", "http://localhost:9000", "issuer URL, OIDC_ISSUER of the server")
+	clientID := flags.String("client-id", "drawboard", "client id, OIDC_CLIENT_ID of the server")
+	clientSecret := flags.String("client-secret", "secret", "client secret, OIDC_CLIENT_SECRET of the server")
+	email := flags.String("email", "dev@example.com", "email of the user that signs in")
+	name := flags.String("name", "Dev User", "name of the user that signs in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	const kid = "mock"
+
+	var mu sync.Mutex
+	codes := make(map[string]mockCode)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 *issuer,
+			"authorization_endpoint": *issuer + "/authorize",
+			"token_endpoint":         *issuer + "/token",
+			"jwks_uri":               *issuer + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"kid": kid,
+				"use": "sig",
+				"alg": "EdDSA",
+				"x":   base64.RawURLEncoding.EncodeToString(public),
+			}},
+		})
+	})
+
+	// signs in right away and sends the browser back with a code
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		redirectURI, err := url.Parse(q.Get("redirect_uri"))
+		if err != nil || q.Get("client_id") != *clientID || q.Get("response_type") != "code" {
+			http.Error(w, "invalid authorization request", http.StatusBadRequest)
+			return
+		}
+		if q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+			return
+		}
+
+		code := randomString()
+		mu.Lock()
+		codes[code] = mockCode{
+			clientID:    q.Get("client_id"),
+			redirectURI: q.Get("redirect_uri"),
+			nonce:       q.Get("nonce"),
+			challenge:   q.Get("code_challenge"),
+			expiresAt:   time.Now().Add(time.Minute),
+		}
+		mu.Unlock()
+
+		back := redirectURI.Query()
+		back.Set("code", code)
+		back.Set("state", q.Get("state"))
+		redirectURI.RawQuery = back.Encode()
+		http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fail := func(code string) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": code})
+		}
+
+		id, secret, ok := r.BasicAuth()
+		if ok {
+			id, _ = url.QueryUnescape(id)
+			secret, _ = url.QueryUnescape(secret)
+		}
+		if !ok || id != *clientID || subtle.ConstantTimeCompare([]byte(secret), []byte(*clientSecret)) != 1 {
+			fail("invalid_client")
+			return
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+			fail("unsupported_grant_type")
+			return
+		}
+
+		mu.Lock()
+		issued, found := codes[r.PostForm.Get("code")]
+		delete(codes, r.PostForm.Get("code"))
+		mu.Unlock()
+
+		challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !found || time.Now().After(issued.expiresAt) || issued.clientID != id ||
+			issued.redirectURI != r.PostForm.Get("redirect_uri") ||
+			base64.RawURLEncoding.EncodeToString(challenge[:]) != issued.challenge {
+			fail("invalid_grant")
+			return
+		}
+
+		now := time.Now()
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+			"iss":            *issuer,
+			"sub":            *email,
+			"aud":            *clientID,
+			"iat":            now.Unix(),
+			"exp":            now.Add(5 * time.Minute).Unix(),
+			"nonce":          issued.nonce,
+			"email":          *email,
+			"email_verified": true,
+			"name":           *name,
+		})
+		token.Header["kid"] = kid
+		idToken, err := token.SignedString(private)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": randomString(),
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token":     idToken,
+		})
+	})
+
+	logger.Info("Mock OIDC issuer %s signs in %s", *issuer, *email)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		return fmt.Errorf("mock-oidc: %w", err)
+	}
+	return nil
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
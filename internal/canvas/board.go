@@ -0,0 +1,240 @@
+package canvas
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shared-drawboard/internal/models"
+)
+
+// recentEventsLimit is how many committed events a board keeps for clients catching up
+const recentEventsLimit = 1000
+
+// undoLimit is how many operations each user can undo
+const undoLimit = 100
+
+// operation is the list of events that undoes or redoes one user action
+type operation []models.Event
+
+type history struct {
+	undo []operation
+	redo []operation
+}
+
+// committed is an event waiting to be handed to the publish func of its commit
+type committed struct {
+	event   models.Event
+	publish func(models.Event)
+}
+
+// Board is the authoritative in-memory copy of a board. Every accepted event
+// gets the next sequence number so all clients converge on the same canvas.
+type Board struct {
+	id           string
+	mu           sync.Mutex
+	state        *State
+	seq          int64
+	recent       []models.Event
+	lastActivity time.Time
+	// undo and redo stacks by user id
+	history map[string]*history
+	// events committed but not published yet, in sequence order
+	outbox []committed
+	// held while the outbox is published, so events go out in sequence order
+	publishMu sync.Mutex
+}
+
+// NewBoard wraps a state rebuilt from storage whose last event had sequence number seq
+func NewBoard(id string, state *State, seq int64) *Board {
+	return &Board{
+		id:           id,
+		state:        state,
+		seq:          seq,
+		recent:       make([]models.Event, 0),
+		lastActivity: time.Now(),
+		history:      make(map[string]*history),
+	}
+}
+
+// Commit stamps the next sequence number on the event and applies it. publish
+// runs once the board is unlocked, so a slow publish does not hold up readers,
+// and still sees the board's events in sequence order. The event becomes the
+// latest undoable operation of its user.
+func (b *Board) Commit(event models.Event, publish func(models.Event)) models.Event {
+	// deferred first so it runs after the unlock
+	defer b.publishCommitted()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	event, inverse := b.commitLocked(event, publish)
+	if event.UserID != "" && len(inverse) > 0 {
+		h := b.userHistory(event.UserID)
+		h.undo = pushOperation(h.undo, inverse)
+		h.redo = nil
+	}
+	return event
+}
+
+// Undo reverts the latest operation of the user. It reports false when there is nothing to undo.
+func (b *Board) Undo(userID string, clientID string, publish func(models.Event)) bool {
+	defer b.publishCommitted()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.userHistory(userID)
+	if len(h.undo) == 0 {
+		return false
+	}
+
+	op := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	if redo := b.commitOperation(op, userID, clientID, models.ActionUndo, publish); len(redo) > 0 {
+		h.redo = pushOperation(h.redo, redo)
+	}
+	return true
+}
+
+// Redo reapplies the latest operation the user undid. It reports false when there is nothing to redo.
+func (b *Board) Redo(userID string, clientID string, publish func(models.Event)) bool {
+	defer b.publishCommitted()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.userHistory(userID)
+	if len(h.redo) == 0 {
+		return false
+	}
+
+	op := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	if undo := b.commitOperation(op, userID, clientID, models.ActionRedo, publish); len(undo) > 0 {
+		h.undo = pushOperation(h.undo, undo)
+	}
+	return true
+}
+
+// commitOperation commits the events of op on behalf of the user and returns
+// the operation that reverts them
+func (b *Board) commitOperation(op operation, userID string, clientID string, action models.EventAction, publish func(models.Event)) operation {
+	reverse := make(operation, 0, len(op))
+	for _, event := range op {
+		event.UserID = userID
+		event.ClientID = clientID
+		event.Action = action
+
+		_, inverse := b.commitLocked(event, publish)
+		// later events have to be reverted first
+		reverse = append(append(operation{}, inverse...), reverse...)
+	}
+	return reverse
+}
+
+func (b *Board) userHistory(userID string) *history {
+	h, ok := b.history[userID]
+	if !ok {
+		h = &history{}
+		b.history[userID] = h
+	}
+	return h
+}
+
+func pushOperation(stack []operation, op operation) []operation {
+	stack = append(stack, op)
+	if len(stack) > undoLimit {
+		stack = append([]operation(nil), stack[len(stack)-undoLimit:]...)
+	}
+	return stack
+}
+
+// commitLocked stamps, sequences and applies the event, returning it with the
+// events that revert it. b.mu must be held.
+func (b *Board) commitLocked(event models.Event, publish func(models.Event)) (models.Event, []models.Event) {
+	inverse := b.state.Inverse(event)
+
+	now := time.Now()
+	b.seq++
+	event.Seq = b.seq
+	event.BoardID = b.id
+	event.Timestamp = now.UnixMilli()
+	event.CreatedAt = strconv.FormatInt(now.Unix(), 10)
+	b.state.Apply(event)
+
+	b.recent = append(b.recent, event)
+	if len(b.recent) > recentEventsLimit {
+		b.recent = append([]models.Event(nil), b.recent[len(b.recent)-recentEventsLimit:]...)
+	}
+	b.lastActivity = now
+
+	if publish != nil {
+		b.outbox = append(b.outbox, committed{event: event, publish: publish})
+	}
+	return event, inverse
+}
+
+// publishCommitted publishes the outbox without holding mu. publishMu makes a
+// concurrent commit wait until the events before its own are out.
+func (b *Board) publishCommitted() {
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+
+	b.mu.Lock()
+	outbox := b.outbox
+	b.outbox = nil
+	b.mu.Unlock()
+
+	for _, c := range outbox {
+		c.publish(c.event)
+	}
+}
+
+// Snapshot returns the current objects together with the sequence number they reflect
+func (b *Board) Snapshot() ([]models.BoardObject, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastActivity = time.Now()
+	return b.state.Objects(), b.seq
+}
+
+// EventsSince returns the committed events after seq. complete is false when
+// the in-memory log no longer reaches back that far, events then holds the
+// whole log so the caller only has to find the older ones elsewhere.
+func (b *Board) EventsSince(seq int64) (events []models.Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if seq == b.seq {
+		return []models.Event{}, true
+	}
+	if seq > b.seq {
+		// the caller saw events this board never committed
+		return nil, false
+	}
+
+	start := 0
+	complete = len(b.recent) > 0 && b.recent[0].Seq <= seq+1
+	if complete {
+		start = int(seq + 1 - b.recent[0].Seq)
+	}
+
+	events = make([]models.Event, len(b.recent)-start)
+	copy(events, b.recent[start:])
+	return events, complete
+}
+
+// Seq returns the sequence number of the last committed event
+func (b *Board) Seq() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.seq
+}
+
+// IdleSince reports whether nothing touched the board for at least d
+func (b *Board) IdleSince(d time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return time.Since(b.lastActivity) >= d
+}
@@ -0,0 +1,237 @@
+package canvas
+
+import (
+	"github.com/shared-drawboard/internal/models"
+)
+
+// State is the materialized object list of a board, built by applying events in order
+type State struct {
+	objects []models.BoardObject
+}
+
+func NewState() *State {
+	return &State{objects: make([]models.BoardObject, 0)}
+}
+
+// FromObjects starts a state from a previously materialized object list
+func FromObjects(objects []models.BoardObject) *State {
+	s := NewState()
+	s.objects = append(s.objects, objects...)
+	return s
+}
+
+// indexOf returns the position of the object with the given id or -1
+func (s *State) indexOf(id string) int {
+	for i := range s.objects {
+		if s.objects[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// legacyIndex returns the position a legacy delete addressed or -1 when
+// there is no object at it
+func (s *State) legacyIndex(index *int) int {
+	if index == nil || *index < 0 || *index >= len(s.objects) {
+		return -1
+	}
+	return *index
+}
+
+// add appends the object unless one with the same id already exists
+func (s *State) add(obj models.BoardObject) {
+	if obj.ID != "" && s.indexOf(obj.ID) != -1 {
+		return
+	}
+	s.objects = append(s.objects, obj)
+}
+
+// Apply mutates the state the same way the drawboard client does for the event
+func (s *State) Apply(event models.Event) {
+	switch data := event.Data.(type) {
+	case models.FreehandDrawData:
+		s.add(models.BoardObject{
+			ID:        data.ID,
+			Type:      event.Tool,
+			Color:     data.Color,
+			Thickness: data.Thickness,
+			Points:    data.Points,
+		})
+
+	case models.ShapeCreateData:
+		s.add(models.BoardObject{
+			ID:        data.ID,
+			Type:      event.Tool,
+			Color:     data.Color,
+			Thickness: data.Thickness,
+			X:         data.X,
+			Y:         data.Y,
+			Width:     data.Width,
+			Height:    data.Height,
+		})
+
+	case models.TextAddData:
+		s.add(models.BoardObject{
+			ID:        data.ID,
+			Type:      "text",
+			Color:     data.Color,
+			Thickness: data.Thickness,
+			X:         data.X,
+			Y:         data.Y,
+			Text:      data.Text,
+		})
+
+	case models.ObjectDeleteData:
+		i := s.indexOf(data.ID)
+		if data.ID == "" {
+			// objects stored before ids all have an empty one
+			i = s.legacyIndex(data.Index)
+		}
+		if i != -1 {
+			s.objects = append(s.objects[:i], s.objects[i+1:]...)
+		}
+
+	case models.ObjectUpdateData:
+		if i := s.indexOf(data.ID); i != -1 && data.ID != "" {
+			updateObject(&s.objects[i], data)
+		}
+
+	default:
+		if event.Type == models.BoardClear {
+			s.objects = make([]models.BoardObject, 0)
+		}
+	}
+}
+
+// Objects returns a copy of the current object list
+func (s *State) Objects() []models.BoardObject {
+	objects := make([]models.BoardObject, len(s.objects))
+	copy(objects, s.objects)
+	return objects
+}
+
+// updateObject applies the move first and then the absolute values of the update
+func updateObject(obj *models.BoardObject, data models.ObjectUpdateData) {
+	if data.DX != 0 || data.DY != 0 {
+		obj.X += data.DX
+		obj.Y += data.DY
+		points := make([]models.Point, len(obj.Points))
+		for i, p := range obj.Points {
+			points[i] = models.Point{X: p.X + data.DX, Y: p.Y + data.DY}
+		}
+		obj.Points = points
+	}
+	if data.X != nil {
+		obj.X = *data.X
+	}
+	if data.Y != nil {
+		obj.Y = *data.Y
+	}
+	if data.Width != nil {
+		obj.Width = *data.Width
+	}
+	if data.Height != nil {
+		obj.Height = *data.Height
+	}
+	if data.Color != nil {
+		obj.Color = *data.Color
+	}
+	if data.Thickness != nil {
+		obj.Thickness = *data.Thickness
+	}
+	if data.Text != nil {
+		obj.Text = *data.Text
+	}
+}
+
+// createEvent returns the event that recreates obj
+func createEvent(obj models.BoardObject) models.Event {
+	switch {
+	case obj.Type == "text":
+		return models.Event{Type: models.TextAdd, Tool: "text", Data: models.TextAddData{
+			ID:        obj.ID,
+			Color:     obj.Color,
+			Thickness: obj.Thickness,
+			X:         obj.X,
+			Y:         obj.Y,
+			Text:      obj.Text,
+		}}
+	case len(obj.Points) > 0:
+		return models.Event{Type: models.FreehandDraw, Tool: obj.Type, Data: models.FreehandDrawData{
+			ID:        obj.ID,
+			Color:     obj.Color,
+			Thickness: obj.Thickness,
+			Points:    obj.Points,
+		}}
+	default:
+		return models.Event{Type: models.ShapeCreate, Tool: obj.Type, Data: models.ShapeCreateData{
+			ID:        obj.ID,
+			Color:     obj.Color,
+			Thickness: obj.Thickness,
+			X:         obj.X,
+			Y:         obj.Y,
+			Width:     obj.Width,
+			Height:    obj.Height,
+		}}
+	}
+}
+
+// Inverse returns the events that undo event when applied right after it.
+// It has to be called before the event itself is applied.
+func (s *State) Inverse(event models.Event) []models.Event {
+	switch data := event.Data.(type) {
+	case models.FreehandDrawData:
+		return s.inverseCreate(data.ID)
+	case models.ShapeCreateData:
+		return s.inverseCreate(data.ID)
+	case models.TextAddData:
+		return s.inverseCreate(data.ID)
+
+	case models.ObjectDeleteData:
+		if i := s.indexOf(data.ID); i != -1 && data.ID != "" {
+			return []models.Event{createEvent(s.objects[i])}
+		}
+
+	case models.ObjectUpdateData:
+		i := s.indexOf(data.ID)
+		if i == -1 || data.ID == "" {
+			return nil
+		}
+		obj := s.objects[i]
+		inverse := models.ObjectUpdateData{
+			ID:        obj.ID,
+			DX:        -data.DX,
+			DY:        -data.DY,
+			X:         &obj.X,
+			Y:         &obj.Y,
+			Width:     &obj.Width,
+			Height:    &obj.Height,
+			Color:     &obj.Color,
+			Thickness: &obj.Thickness,
+		}
+		if obj.Text != "" {
+			inverse.Text = &obj.Text
+		}
+		return []models.Event{{Type: models.ObjectUpdate, Tool: event.Tool, Data: inverse}}
+
+	default:
+		if event.Type == models.BoardClear {
+			inverse := make([]models.Event, 0, len(s.objects))
+			for _, obj := range s.objects {
+				inverse = append(inverse, createEvent(obj))
+			}
+			return inverse
+		}
+	}
+	return nil
+}
+
+// inverseCreate deletes the created object, unless the id is already taken
+// and the create is a no-op
+func (s *State) inverseCreate(id string) []models.Event {
+	if id == "" || s.indexOf(id) != -1 {
+		return nil
+	}
+	return []models.Event{{Type: models.ObjectDelete, Tool: "select", Data: models.ObjectDeleteData{ID: id}}}
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -19,8 +20,35 @@ type DB interface {
 	SaveUserDB(ctx context.Context, u models.User) (id string, err error)
 	FindBy(ctx context.Context, field string, value interface{}) (*User, error)
 	CreateSession(ctx context.Context, session models.SessionDTO) (string, error)
-	UpdateSession(ctx context.Context, uid string, newToken string) (string, error)
+	RotateSession(ctx context.Context, session models.SessionDTO, oldHash string) (bool, error)
+	FindSession(ctx context.Context, id string) (*Session, error)
+	FindSessionsByUser(ctx context.Context, userID string) ([]Session, error)
+	DeleteSession(ctx context.Context, id string) (bool, error)
+	DeleteSessions(ctx context.Context, userID string) error
 	BatchSave(ctx context.Context, batch []interface{}) error
+	FindEvents(ctx context.Context, boardID string, afterID string) ([]Event, error)
+	FindEventsBySeq(ctx context.Context, boardID string, afterSeq int64, beforeSeq int64) ([]Event, error)
+	SaveSnapshot(ctx context.Context, snap models.Snapshot) (string, error)
+	FindLatestSnapshot(ctx context.Context, boardID string) (*Snapshot, error)
+	FindSnapshotAtSeq(ctx context.Context, boardID string, maxSeq int64) (*Snapshot, error)
+	FindEventsUpToSeq(ctx context.Context, boardID string, afterID string, maxSeq int64) ([]Event, error)
+	FindSeqAtTime(ctx context.Context, boardID string, ts int64) (int64, error)
+	CreateBoard(ctx context.Context, b models.Board) (string, error)
+	FindBoard(ctx context.Context, id string) (*Board, error)
+	FindBoardsByOwner(ctx context.Context, ownerID string, includeArchived bool) ([]Board, error)
+	UpdateBoard(ctx context.Context, id string, update models.BoardUpdateDTO) error
+	DeleteBoard(ctx context.Context, id string) error
+	FindBoardsByIDs(ctx context.Context, ids []string, includeArchived bool) ([]Board, error)
+	SaveBoardMember(ctx context.Context, member models.BoardMember) error
+	FindBoardMember(ctx context.Context, boardID string, userID string) (*BoardMember, error)
+	FindBoardMembers(ctx context.Context, boardID string) ([]BoardMember, error)
+	FindMemberships(ctx context.Context, userID string) ([]BoardMember, error)
+	DeleteBoardMember(ctx context.Context, boardID string, userID string) error
+	CreateInvite(ctx context.Context, invite models.Invite) (string, error)
+	FindInvite(ctx context.Context, id string) (*Invite, error)
+	FindInvitesByBoard(ctx context.Context, boardID string) ([]Invite, error)
+	DeleteInvite(ctx context.Context, boardID string, id string) (bool, error)
+	IncrementInviteUses(ctx context.Context, id string) error
 }
 
 type MongoDB struct {
@@ -29,9 +57,13 @@ type MongoDB struct {
 }
 
 const (
-	USER_COLLECTION    = "users"
-	SESSION_COLLECTION = "sessions"
-	EVENTS_COLLECTION  = "events"
+	USER_COLLECTION      = "users"
+	SESSION_COLLECTION   = "sessions"
+	EVENTS_COLLECTION    = "events"
+	BOARDS_COLLECTION    = "boards"
+	SNAPSHOTS_COLLECTION = "snapshots"
+	MEMBERS_COLLECTION   = "board_members"
+	INVITES_COLLECTION   = "invites"
 )
 
 func New() (*MongoDB, error) {
@@ -51,7 +83,86 @@ func New() (*MongoDB, error) {
 	db := client.Database(dbConfig["db_name"].(string))
 	logger.Info("Database connected successfully")
 
-	return &MongoDB{client: client, db: db}, nil
+	m := &MongoDB{client: client, db: db}
+	if err := m.migrateSessionExpiry(ctx); err != nil {
+		return nil, fmt.Errorf("DB: %w", err)
+	}
+	if err := m.ensureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("DB: %w", err)
+	}
+
+	return m, nil
+}
+
+// ensureIndexes creates the indexes used by replay, history and audit queries
+func (m *MongoDB) ensureIndexes(ctx context.Context) error {
+	indexes := map[string][]mongo.IndexModel{
+		EVENTS_COLLECTION: {
+			// events stored before sequencing all have seq 0
+			{
+				Keys: bson.D{{Key: "board_id", Value: 1}, {Key: "seq", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"seq": bson.M{"$gt": 0}}),
+			},
+			{Keys: bson.D{{Key: "board_id", Value: 1}, {Key: "ts", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "ts", Value: 1}}},
+			{Keys: bson.D{{Key: "client_id", Value: 1}}},
+		},
+		BOARDS_COLLECTION: {
+			{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+		},
+		SNAPSHOTS_COLLECTION: {
+			{Keys: bson.D{{Key: "board_id", Value: 1}, {Key: "last_event_id", Value: -1}}},
+			{Keys: bson.D{{Key: "board_id", Value: 1}, {Key: "last_seq", Value: -1}}},
+		},
+		MEMBERS_COLLECTION: {
+			{
+				Keys:    bson.D{{Key: "board_id", Value: 1}, {Key: "user_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		},
+		SESSION_COLLECTION: {
+			// removes a session once its refresh token expired
+			{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		INVITES_COLLECTION: {
+			{Keys: bson.D{{Key: "board_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+	}
+
+	for collection, idx := range indexes {
+		if _, err := m.db.Collection(collection).Indexes().CreateMany(ctx, idx); err != nil {
+			return fmt.Errorf("failed to create %s indexes: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// migrateSessionExpiry turns the unix second strings older versions stored
+// as session expiry into dates, the TTL index ignores anything else. Needs
+// MongoDB 4.2 for the update pipeline.
+func (m *MongoDB) migrateSessionExpiry(ctx context.Context) error {
+	col := m.db.Collection(SESSION_COLLECTION)
+
+	filter := bson.M{"expires_at": bson.M{"$type": "string"}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			// a value that is no number becomes 1970, so the session is removed
+			"expires_at": bson.M{"$toDate": bson.M{"$multiply": bson.A{
+				bson.M{"$convert": bson.M{"input": "$expires_at", "to": "long", "onError": 0}},
+				1000,
+			}}},
+		}}},
+	}
+	if _, err := col.UpdateMany(ctx, filter, update); err != nil {
+		logger.Error("Update failed: %v", err)
+		return fmt.Errorf("failed to migrate session expiry: %w", err)
+	}
+	return nil
 }
 
 func (m *MongoDB) SaveUserDB(ctx context.Context, u models.User) (id string, err error) {
@@ -100,9 +211,12 @@ func ClearPreviousSessions(ctx context.Context, col *mongo.Collection, userID st
 func (m *MongoDB) CreateSession(ctx context.Context, s models.SessionDTO) (string, error) {
 	col := m.db.Collection(SESSION_COLLECTION)
 
+	// every device keeps its own session
 	session := Session{
 		UserID:     s.UserID,
 		TokenHash:  s.TokenHash,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
 		ExpiresAt:  s.ExpiresAt,
 		CreatedAt:  s.CreatedAt,
 		LastUsedAt: s.LastUsedAt,
@@ -112,13 +226,7 @@ func (m *MongoDB) CreateSession(ctx context.Context, s models.SessionDTO) (strin
 		session.ID = primitive.NewObjectID()
 	}
 
-	err := ClearPreviousSessions(ctx, col, s.UserID)
-	if err != nil {
-		logger.Error("Insert failed: %v", err)
-		return "", fmt.Errorf("failed to clear previous sessions: %w", err)
-	}
-
-	_, err = col.InsertOne(ctx, session)
+	_, err := col.InsertOne(ctx, session)
 	if err != nil {
 		logger.Error("Insert failed: %v", err)
 		return "", fmt.Errorf("failed to insert user: %w", err)
@@ -126,43 +234,599 @@ func (m *MongoDB) CreateSession(ctx context.Context, s models.SessionDTO) (strin
 	return session.ID.Hex(), nil
 }
 
-func (m *MongoDB) UpdateSession(ctx context.Context, uid string, newTokenHash string) (string, error) {
+// MaxRotatedHashes is how many replaced refresh tokens a session remembers
+// to recognize when one of them is used again
+const MaxRotatedHashes = 100
+
+// RotateSession replaces the refresh token hash oldHash of a session with the
+// new one and records the device that used it. It reports false when the
+// session no longer holds oldHash, because it ended or was rotated meanwhile.
+func (m *MongoDB) RotateSession(ctx context.Context, s models.SessionDTO, oldHash string) (bool, error) {
 	col := m.db.Collection(SESSION_COLLECTION)
 
-	filter := bson.M{"user_id": uid}
+	oid, err := primitive.ObjectIDFromHex(s.ID)
+	if err != nil {
+		return false, nil
+	}
+
+	filter := bson.M{"_id": oid, "user_id": s.UserID, "token_hash": oldHash}
 	update := bson.M{
 		"$set": bson.M{
-			"token_hash":   newTokenHash,
-			"created_at":   strconv.FormatInt(time.Now().Unix(), 10),
+			"token_hash":   s.TokenHash,
+			"user_agent":   s.UserAgent,
+			"ip":           s.IP,
 			"last_used_at": strconv.FormatInt(time.Now().Unix(), 10),
-			"expires_at":   strconv.FormatInt(time.Now().Add(24*7*time.Hour).Unix(), 10),
+			"expires_at":   time.Now().Add(24 * 7 * time.Hour),
+		},
+		"$push": bson.M{
+			"rotated_hashes": bson.M{"$each": bson.A{oldHash}, "$slice": -MaxRotatedHashes},
 		},
 	}
 
-	doc, err := col.UpdateOne(ctx, filter, update)
+	res, err := col.UpdateOne(ctx, filter, update)
 	if err != nil {
 		logger.Error("Update failed: %v", err)
-		return "", fmt.Errorf("failed to insert user: %w", err)
+		return false, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (m *MongoDB) FindSession(ctx context.Context, id string) (*Session, error) {
+	col := m.db.Collection(SESSION_COLLECTION)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
 	}
 
-	return doc.UpsertedID.(string), nil
+	var session Session
+	if err := col.FindOne(ctx, bson.M{"_id": oid}).Decode(&session); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
 }
 
-// func (m *MongoDB) DeleteSession(ctx context.Context) {
-// 	col := m.db.Collection(SESSION_COLLECTION)
+func (m *MongoDB) FindSessionsByUser(ctx context.Context, userID string) ([]Session, error) {
+	col := m.db.Collection(SESSION_COLLECTION)
 
-// 	_, err := col.DeleteOne()
+	opts := options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}})
+	cursor, err := col.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions: %w", err)
+	}
 
-// }
+	sessions := make([]Session, 0)
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeleteSession ends one session, reporting false when it did not exist
+func (m *MongoDB) DeleteSession(ctx context.Context, id string) (bool, error) {
+	col := m.db.Collection(SESSION_COLLECTION)
 
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+
+	res, err := col.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		logger.Error("Delete failed: %v", err)
+		return false, fmt.Errorf("failed to delete session: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// DeleteSessions ends every session of the user
+func (m *MongoDB) DeleteSessions(ctx context.Context, userID string) error {
+	if err := ClearPreviousSessions(ctx, m.db.Collection(SESSION_COLLECTION), userID); err != nil {
+		logger.Error("Delete failed: %v", err)
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
+
+// BatchSave is safe to retry, events already stored by an earlier attempt
+// are rejected by the (board_id, seq) index and skipped
 func (m *MongoDB) BatchSave(ctx context.Context, batch []interface{}) error {
 	col := m.db.Collection(EVENTS_COLLECTION)
 
-	_, err := col.InsertMany(ctx, batch)
-	if err != nil {
+	_, err := col.InsertMany(ctx, batch, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicateKeys(err) {
 		logger.Error("Update failed: %v", err)
 		return fmt.Errorf("failed to insert batch data: %w", err)
 	}
 
 	return nil
 }
+
+// onlyDuplicateKeys reports whether every write of a failed insert was
+// refused because the event is already stored, any other error must be retried
+func onlyDuplicateKeys(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) {
+		return false
+	}
+	if bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != 11000 {
+			return false
+		}
+	}
+	return true
+}
+
+// FindEvents returns the stored events of a board in insertion order,
+// only those after afterID when it is set
+func (m *MongoDB) FindEvents(ctx context.Context, boardID string, afterID string) ([]Event, error) {
+	col := m.db.Collection(EVENTS_COLLECTION)
+
+	filter := bson.M{"board_id": boardID}
+	if afterID != "" {
+		oid, err := primitive.ObjectIDFromHex(afterID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event id: %w", err)
+		}
+		filter["_id"] = bson.M{"$gt": oid}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find events: %w", err)
+	}
+
+	events := make([]Event, 0)
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode events: %w", err)
+	}
+	return events, nil
+}
+
+// FindEventsBySeq returns the stored events of a board with afterSeq < seq < beforeSeq ordered by seq
+func (m *MongoDB) FindEventsBySeq(ctx context.Context, boardID string, afterSeq int64, beforeSeq int64) ([]Event, error) {
+	col := m.db.Collection(EVENTS_COLLECTION)
+
+	filter := bson.M{
+		"board_id": boardID,
+		"seq":      bson.M{"$gt": afterSeq, "$lt": beforeSeq},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find events: %w", err)
+	}
+
+	events := make([]Event, 0)
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode events: %w", err)
+	}
+	return events, nil
+}
+
+func (m *MongoDB) SaveSnapshot(ctx context.Context, snap models.Snapshot) (string, error) {
+	col := m.db.Collection(SNAPSHOTS_COLLECTION)
+
+	lastEventID, err := primitive.ObjectIDFromHex(snap.LastEventID)
+	if err != nil {
+		return "", fmt.Errorf("invalid event id: %w", err)
+	}
+
+	snapshot := Snapshot{
+		BoardID:     snap.BoardID,
+		Objects:     snap.Objects,
+		LastEventID: lastEventID,
+		LastSeq:     snap.LastSeq,
+		CreatedAt:   snap.CreatedAt,
+	}
+
+	if snapshot.ID.IsZero() {
+		snapshot.ID = primitive.NewObjectID()
+	}
+
+	_, err = col.InsertOne(ctx, snapshot)
+	if err != nil {
+		logger.Error("Insert failed: %v", err)
+		return "", fmt.Errorf("failed to insert snapshot: %w", err)
+	}
+	return snapshot.ID.Hex(), nil
+}
+
+// FindLatestSnapshot returns nil without an error when the board has no snapshot
+func (m *MongoDB) FindLatestSnapshot(ctx context.Context, boardID string) (*Snapshot, error) {
+	col := m.db.Collection(SNAPSHOTS_COLLECTION)
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "last_event_id", Value: -1}})
+
+	var snapshot Snapshot
+	if err := col.FindOne(ctx, bson.M{"board_id": boardID}, opts).Decode(&snapshot); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// FindSnapshotAtSeq returns the latest snapshot that covers no event after
+// maxSeq, or nil without an error when there is none
+func (m *MongoDB) FindSnapshotAtSeq(ctx context.Context, boardID string, maxSeq int64) (*Snapshot, error) {
+	col := m.db.Collection(SNAPSHOTS_COLLECTION)
+
+	filter := bson.M{"board_id": boardID, "last_seq": bson.M{"$lte": maxSeq}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "last_seq", Value: -1}, {Key: "last_event_id", Value: -1}})
+
+	var snapshot Snapshot
+	if err := col.FindOne(ctx, filter, opts).Decode(&snapshot); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// FindEventsUpToSeq returns the stored events of a board after afterID in
+// insertion order, leaving out those sequenced after maxSeq
+func (m *MongoDB) FindEventsUpToSeq(ctx context.Context, boardID string, afterID string, maxSeq int64) ([]Event, error) {
+	col := m.db.Collection(EVENTS_COLLECTION)
+
+	filter := bson.M{"board_id": boardID, "seq": bson.M{"$lte": maxSeq}}
+	if afterID != "" {
+		oid, err := primitive.ObjectIDFromHex(afterID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event id: %w", err)
+		}
+		filter["_id"] = bson.M{"$gt": oid}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find events: %w", err)
+	}
+
+	events := make([]Event, 0)
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode events: %w", err)
+	}
+	return events, nil
+}
+
+// FindSeqAtTime returns the sequence number of the last stored event committed
+// at or before ts (unix milliseconds), 0 when there is none
+func (m *MongoDB) FindSeqAtTime(ctx context.Context, boardID string, ts int64) (int64, error) {
+	col := m.db.Collection(EVENTS_COLLECTION)
+
+	filter := bson.M{"board_id": boardID, "ts": bson.M{"$lte": ts}, "seq": bson.M{"$gt": 0}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})
+
+	var event Event
+	if err := col.FindOne(ctx, filter, opts).Decode(&event); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return event.Seq, nil
+}
+
+func (m *MongoDB) CreateBoard(ctx context.Context, b models.Board) (string, error) {
+	col := m.db.Collection(BOARDS_COLLECTION)
+
+	board := Board{
+		Name:      b.Name,
+		OwnerID:   b.OwnerID,
+		Archived:  b.Archived,
+		Public:    b.Public,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+
+	if board.ID.IsZero() {
+		board.ID = primitive.NewObjectID()
+	}
+
+	_, err := col.InsertOne(ctx, board)
+	if err != nil {
+		logger.Error("Insert failed: %v", err)
+		return "", fmt.Errorf("failed to insert board: %w", err)
+	}
+
+	return board.ID.Hex(), nil
+}
+
+// FindBoard returns nil without an error when no board matches the id
+func (m *MongoDB) FindBoard(ctx context.Context, id string) (*Board, error) {
+	col := m.db.Collection(BOARDS_COLLECTION)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	var board Board
+	if err := col.FindOne(ctx, bson.M{"_id": oid}).Decode(&board); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &board, nil
+}
+
+func (m *MongoDB) FindBoardsByOwner(ctx context.Context, ownerID string, includeArchived bool) ([]Board, error) {
+	col := m.db.Collection(BOARDS_COLLECTION)
+
+	filter := bson.M{"owner_id": ownerID}
+	if !includeArchived {
+		filter["archived"] = false
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find boards: %w", err)
+	}
+
+	boards := make([]Board, 0)
+	if err := cursor.All(ctx, &boards); err != nil {
+		return nil, fmt.Errorf("failed to decode boards: %w", err)
+	}
+	return boards, nil
+}
+
+func (m *MongoDB) UpdateBoard(ctx context.Context, id string, update models.BoardUpdateDTO) error {
+	col := m.db.Collection(BOARDS_COLLECTION)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid board id: %w", err)
+	}
+
+	set := bson.M{"updated_at": strconv.FormatInt(time.Now().Unix(), 10)}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Archived != nil {
+		set["archived"] = *update.Archived
+	}
+	if update.Public != nil {
+		set["public"] = *update.Public
+	}
+
+	_, err = col.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": set})
+	if err != nil {
+		logger.Error("Update failed: %v", err)
+		return fmt.Errorf("failed to update board: %w", err)
+	}
+	return nil
+}
+
+// DeleteBoard removes the board together with its stored events, snapshots, members and invites
+func (m *MongoDB) DeleteBoard(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid board id: %w", err)
+	}
+
+	if _, err := m.db.Collection(EVENTS_COLLECTION).DeleteMany(ctx, bson.M{"board_id": id}); err != nil {
+		logger.Error("Delete failed: %v", err)
+		return fmt.Errorf("failed to delete board events: %w", err)
+	}
+
+	if _, err := m.db.Collection(SNAPSHOTS_COLLECTION).DeleteMany(ctx, bson.M{"board_id": id}); err != nil {
+		logger.Error("Delete failed: %v", err)
+		return fmt.Errorf("failed to delete board snapshots: %w", err)
+	}
+
+	if _, err := m.db.Collection(MEMBERS_COLLECTION).DeleteMany(ctx, bson.M{"board_id": id}); err != nil {
+		logger.Error("Delete failed: %v", err)
+		return fmt.Errorf("failed to delete board members: %w", err)
+	}
+
+	if _, err := m.db.Collection(INVITES_COLLECTION).DeleteMany(ctx, bson.M{"board_id": id}); err != nil {
+		logger.Error("Delete failed: %v", err)
+		return fmt.Errorf("failed to delete board invites: %w", err)
+	}
+
+	if _, err := m.db.Collection(BOARDS_COLLECTION).DeleteOne(ctx, bson.M{"_id": oid}); err != nil {
+		logger.Error("Delete failed: %v", err)
+		return fmt.Errorf("failed to delete board: %w", err)
+	}
+	return nil
+}
+
+// FindBoardsByIDs skips ids that are invalid or match no board
+func (m *MongoDB) FindBoardsByIDs(ctx context.Context, ids []string, includeArchived bool) ([]Board, error) {
+	col := m.db.Collection(BOARDS_COLLECTION)
+
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	if len(oids) == 0 {
+		return []Board{}, nil
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": oids}}
+	if !includeArchived {
+		filter["archived"] = false
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find boards: %w", err)
+	}
+
+	boards := make([]Board, 0)
+	if err := cursor.All(ctx, &boards); err != nil {
+		return nil, fmt.Errorf("failed to decode boards: %w", err)
+	}
+	return boards, nil
+}
+
+// SaveBoardMember adds the member or changes the role of an existing one
+func (m *MongoDB) SaveBoardMember(ctx context.Context, member models.BoardMember) error {
+	col := m.db.Collection(MEMBERS_COLLECTION)
+
+	filter := bson.M{"board_id": member.BoardID, "user_id": member.UserID}
+	update := bson.M{
+		"$set":         bson.M{"role": string(member.Role)},
+		"$setOnInsert": bson.M{"created_at": member.CreatedAt},
+	}
+
+	_, err := col.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		logger.Error("Update failed: %v", err)
+		return fmt.Errorf("failed to save board member: %w", err)
+	}
+	return nil
+}
+
+// FindBoardMember returns nil without an error when the user is not a member
+func (m *MongoDB) FindBoardMember(ctx context.Context, boardID string, userID string) (*BoardMember, error) {
+	col := m.db.Collection(MEMBERS_COLLECTION)
+
+	var member BoardMember
+	if err := col.FindOne(ctx, bson.M{"board_id": boardID, "user_id": userID}).Decode(&member); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (m *MongoDB) FindBoardMembers(ctx context.Context, boardID string) ([]BoardMember, error) {
+	return m.findMembers(ctx, bson.M{"board_id": boardID})
+}
+
+// FindMemberships returns the boards shared with the user
+func (m *MongoDB) FindMemberships(ctx context.Context, userID string) ([]BoardMember, error) {
+	return m.findMembers(ctx, bson.M{"user_id": userID})
+}
+
+func (m *MongoDB) findMembers(ctx context.Context, filter bson.M) ([]BoardMember, error) {
+	col := m.db.Collection(MEMBERS_COLLECTION)
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find board members: %w", err)
+	}
+
+	members := make([]BoardMember, 0)
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode board members: %w", err)
+	}
+	return members, nil
+}
+
+func (m *MongoDB) DeleteBoardMember(ctx context.Context, boardID string, userID string) error {
+	col := m.db.Collection(MEMBERS_COLLECTION)
+
+	if _, err := col.DeleteOne(ctx, bson.M{"board_id": boardID, "user_id": userID}); err != nil {
+		logger.Error("Delete failed: %v", err)
+		return fmt.Errorf("failed to delete board member: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoDB) CreateInvite(ctx context.Context, i models.Invite) (string, error) {
+	col := m.db.Collection(INVITES_COLLECTION)
+
+	invite := Invite{
+		ID:        primitive.NewObjectID(),
+		BoardID:   i.BoardID,
+		Role:      string(i.Role),
+		CreatedBy: i.CreatedBy,
+		CreatedAt: i.CreatedAt,
+		ExpiresAt: i.ExpiresAt,
+	}
+
+	if _, err := col.InsertOne(ctx, invite); err != nil {
+		logger.Error("Insert failed: %v", err)
+		return "", fmt.Errorf("failed to insert invite: %w", err)
+	}
+	return invite.ID.Hex(), nil
+}
+
+// FindInvite returns nil without an error when the invite does not exist or was revoked
+func (m *MongoDB) FindInvite(ctx context.Context, id string) (*Invite, error) {
+	col := m.db.Collection(INVITES_COLLECTION)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	var invite Invite
+	if err := col.FindOne(ctx, bson.M{"_id": oid}).Decode(&invite); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (m *MongoDB) FindInvitesByBoard(ctx context.Context, boardID string) ([]Invite, error) {
+	col := m.db.Collection(INVITES_COLLECTION)
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := col.Find(ctx, bson.M{"board_id": boardID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find invites: %w", err)
+	}
+
+	invites := make([]Invite, 0)
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, fmt.Errorf("failed to decode invites: %w", err)
+	}
+	return invites, nil
+}
+
+// DeleteInvite reports whether an invite of the board matched the id
+func (m *MongoDB) DeleteInvite(ctx context.Context, boardID string, id string) (bool, error) {
+	col := m.db.Collection(INVITES_COLLECTION)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+
+	res, err := col.DeleteOne(ctx, bson.M{"_id": oid, "board_id": boardID})
+	if err != nil {
+		logger.Error("Delete failed: %v", err)
+		return false, fmt.Errorf("failed to delete invite: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (m *MongoDB) IncrementInviteUses(ctx context.Context, id string) error {
+	col := m.db.Collection(INVITES_COLLECTION)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid invite id: %w", err)
+	}
+
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$inc": bson.M{"uses": 1}}); err != nil {
+		logger.Error("Update failed: %v", err)
+		return fmt.Errorf("failed to update invite: %w", err)
+	}
+	return nil
+}
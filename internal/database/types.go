@@ -1,6 +1,12 @@
 package database
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"github.com/shared-drawboard/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type User struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty"`
@@ -9,11 +15,69 @@ type User struct {
 	Password string             `bson:"password"`
 }
 
+// Session is one signed in device. ExpiresAt is a date so the TTL index on
+// it removes the session once its refresh token expired.
 type Session struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
 	UserID     string             `bson:"user_id" json:"user_id"`
 	TokenHash  string             `bson:"token_hash" json:"token_hash"`
-	ExpiresAt  string             `bson:"expires_at" json:"expires_at"`
+	UserAgent  string             `bson:"user_agent" json:"user_agent"`
+	IP         string             `bson:"ip" json:"ip"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
 	CreatedAt  string             `bson:"created_at" json:"created_at"`
 	LastUsedAt string             `bson:"last_used_at" json:"last_used_at"`
+	// hashes of the refresh tokens this session already rotated out, newest last
+	RotatedHashes []string `bson:"rotated_hashes,omitempty" json:"-"`
+}
+
+type Board struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	Name      string             `bson:"name" json:"name"`
+	OwnerID   string             `bson:"owner_id" json:"owner_id"`
+	Archived  bool               `bson:"archived" json:"archived"`
+	Public    bool               `bson:"public" json:"public"`
+	CreatedAt string             `bson:"created_at" json:"created_at"`
+	UpdatedAt string             `bson:"updated_at" json:"updated_at"`
+}
+
+type BoardMember struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	BoardID   string             `bson:"board_id" json:"board_id"`
+	UserID    string             `bson:"user_id" json:"user_id"`
+	Role      string             `bson:"role" json:"role"`
+	CreatedAt string             `bson:"created_at" json:"created_at"`
+}
+
+type Invite struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	BoardID   string             `bson:"board_id" json:"board_id"`
+	Role      string             `bson:"role" json:"role"`
+	CreatedBy string             `bson:"created_by" json:"created_by"`
+	CreatedAt string             `bson:"created_at" json:"created_at"`
+	ExpiresAt string             `bson:"expires_at" json:"expires_at"`
+	Uses      int64              `bson:"uses" json:"uses"`
+}
+
+// Event keeps the data payload raw, it is decoded by event type in the service
+type Event struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	BoardID   string             `bson:"board_id" json:"board_id"`
+	Seq       int64              `bson:"seq" json:"seq"`
+	UserID    string             `bson:"user_id" json:"user_id"`
+	ClientID  string             `bson:"client_id" json:"client_id"`
+	Action    string             `bson:"action,omitempty" json:"action,omitempty"`
+	Type      string             `bson:"type" json:"type"`
+	Tool      string             `bson:"tool" json:"tool"`
+	CreatedAt string             `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	Timestamp int64              `bson:"ts" json:"ts"`
+	Data      bson.RawValue      `bson:"data" json:"data"`
+}
+
+type Snapshot struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"_id,omitempty"`
+	BoardID     string               `bson:"board_id" json:"board_id"`
+	Objects     []models.BoardObject `bson:"objects" json:"objects"`
+	LastEventID primitive.ObjectID   `bson:"last_event_id" json:"last_event_id"`
+	LastSeq     int64                `bson:"last_seq" json:"last_seq"`
+	CreatedAt   string               `bson:"created_at" json:"created_at"`
 }
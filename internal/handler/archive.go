@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/shared-drawboard/internal/middleware"
+	"github.com/shared-drawboard/internal/service"
+)
+
+// MaxArchiveSize limits the body of an import request
+const MaxArchiveSize = 64 << 20
+
+// exportArchiveHandler downloads the board as a JSON archive with its whole
+// event log, or only its current objects with ?snapshot=true
+func (h *Handler) exportArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	snapshotOnly := r.URL.Query().Get("snapshot") == "true"
+
+	archive, err := h.Service.ExportBoard(r.Context(), userID, mux.Vars(r)["id"], snapshotOnly)
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, archive.Board.ID))
+	json.NewEncoder(w).Encode(archive)
+}
+
+// importArchiveHandler creates a new board owned by the caller from an archive
+func (h *Handler) importArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxArchiveSize))
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+
+	archive, err := service.ParseArchive(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	board, err := h.Service.ImportBoard(r.Context(), userID, archive)
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(board)
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shared-drawboard/internal/middleware"
+	"github.com/shared-drawboard/internal/models"
+	"github.com/shared-drawboard/internal/service"
+)
+
+func boardErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrBoardNotFound), errors.Is(err, service.ErrUserNotFound),
+		errors.Is(err, service.ErrInviteNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrBoardForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidBoardName), errors.Is(err, service.ErrInvalidHistoryQuery),
+		errors.Is(err, service.ErrInvalidArchive), errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, service.ErrOwnerMember), errors.Is(err, service.ErrInvalidInviteExpiry):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidInvite):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) createBoardHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	var req models.Board
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+
+	board, err := h.Service.CreateBoard(r.Context(), userID, req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(board)
+}
+
+func (h *Handler) listBoardsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	includeArchived := r.URL.Query().Get("archived") == "true"
+
+	boards, err := h.Service.ListBoards(r.Context(), userID, includeArchived)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"boards": boards})
+}
+
+func (h *Handler) getBoardHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	board, err := h.Service.GetBoard(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(board)
+}
+
+// updateBoardHandler renames, archives and/or publishes a board
+func (h *Handler) updateBoardHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	var req models.BoardUpdateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+
+	board, err := h.Service.UpdateBoard(r.Context(), userID, mux.Vars(r)["id"], req)
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	// anonymous watchers of a board that is no longer public are turned
+	// away when they reconnect, members join again. Nobody may draw on an
+	// archived board, so its clients are turned away too.
+	if (req.Public != nil && !*req.Public) || (req.Archived != nil && *req.Archived) {
+		h.Manager.DisconnectAll(board.ID)
+	}
+
+	json.NewEncoder(w).Encode(board)
+}
+
+func (h *Handler) deleteBoardHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	boardID := mux.Vars(r)["id"]
+	disconnect := func() { h.Manager.DisconnectAll(boardID) }
+	if err := h.Service.DeleteBoard(r.Context(), userID, boardID, disconnect); err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// boardHistoryHandler returns the board as it was after sequence number ?seq=
+// or at ?at= (unix milliseconds or RFC 3339), the latest state without either
+func (h *Handler) boardHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	boardID := mux.Vars(r)["id"]
+	query := r.URL.Query()
+
+	var (
+		history *models.BoardHistoryDTO
+		err     error
+	)
+
+	switch {
+	case query.Get("seq") != "":
+		seq, perr := strconv.ParseInt(query.Get("seq"), 10, 64)
+		if perr != nil {
+			http.Error(w, "Invalid seq value", http.StatusBadRequest)
+			return
+		}
+		history, err = h.Service.BoardAtSeq(r.Context(), userID, boardID, seq)
+
+	case query.Get("at") != "":
+		at, perr := parseHistoryTime(query.Get("at"))
+		if perr != nil {
+			http.Error(w, "Invalid at value", http.StatusBadRequest)
+			return
+		}
+		history, err = h.Service.BoardAtTime(r.Context(), userID, boardID, at)
+
+	default:
+		history, err = h.Service.BoardAtSeq(r.Context(), userID, boardID, math.MaxInt64)
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(history)
+}
+
+// parseHistoryTime accepts unix milliseconds or an RFC 3339 timestamp
+func parseHistoryTime(value string) (int64, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixMilli(), nil
+}
+
+// publicBoardHandler returns the current state of a public board to anyone
+func (h *Handler) publicBoardHandler(w http.ResponseWriter, r *http.Request) {
+	board, err := h.Service.PublicBoardState(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(board)
+}
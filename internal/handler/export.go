@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/shared-drawboard/internal/middleware"
+	"github.com/shared-drawboard/internal/render"
+	"github.com/shared-drawboard/pkg/logger"
+)
+
+// parseRenderOptions reads ?width=&height=&background=&crop=x,y,w,h
+func parseRenderOptions(r *http.Request) (render.Options, error) {
+	query := r.URL.Query()
+	opts := render.Options{Background: query.Get("background")}
+
+	var err error
+	if v := query.Get("width"); v != "" {
+		if opts.Width, err = strconv.Atoi(v); err != nil {
+			return opts, render.ErrInvalidOptions
+		}
+	}
+	if v := query.Get("height"); v != "" {
+		if opts.Height, err = strconv.Atoi(v); err != nil {
+			return opts, render.ErrInvalidOptions
+		}
+	}
+	if v := query.Get("crop"); v != "" {
+		parts := strings.Split(v, ",")
+		if len(parts) != 4 {
+			return opts, render.ErrInvalidOptions
+		}
+		values := make([]float64, 4)
+		for i, part := range parts {
+			if values[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+				return opts, render.ErrInvalidOptions
+			}
+		}
+		// an empty crop would otherwise mean the bounds of the drawing
+		if values[2] <= 0 || values[3] <= 0 {
+			return opts, render.ErrInvalidOptions
+		}
+		opts.Crop = render.Rect{X: values[0], Y: values[1], Width: values[2], Height: values[3]}
+	}
+
+	return opts, opts.Validate()
+}
+
+func (h *Handler) exportSVGHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	opts, err := parseRenderOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Background == "" {
+		opts.Background = "white"
+	}
+
+	board, objects, err := h.Service.ReadBoardObjects(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.svg"`, board.ID))
+	w.Write(render.SVG(objects, opts))
+}
+
+func (h *Handler) exportPNGHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	opts, err := parseRenderOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Background == "" {
+		opts.Background = "white"
+	}
+
+	board, objects, err := h.Service.ReadBoardObjects(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	data, err := render.PNG(objects, opts)
+	if errors.Is(err, render.ErrInvalidOptions) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.Error("PNG export failed: %s", err)
+		http.Error(w, "Failed to render board", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.png"`, board.ID))
+	w.Write(data)
+}
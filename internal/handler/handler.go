@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,21 +11,36 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	ws "github.com/gorilla/websocket"
+	"github.com/shared-drawboard/internal/middleware"
 	"github.com/shared-drawboard/internal/models"
 	"github.com/shared-drawboard/internal/service"
 	"github.com/shared-drawboard/internal/websocket"
 	"github.com/shared-drawboard/pkg/auth"
 	"github.com/shared-drawboard/pkg/helper"
 	"github.com/shared-drawboard/pkg/logger"
+	"github.com/shared-drawboard/pkg/oidc"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Handler struct {
 	Router  *mux.Router
 	Service *service.Service
+	Manager *websocket.Manager
+	// nil unless single sign-on is configured
+	OIDC *oidc.Provider
 }
 
 func New() (*Handler, error) {
+	// a broken key setup should stop the server, not every sign in
+	if err := auth.LoadKeys(); err != nil {
+		return nil, err
+	}
+
+	oidcConfig, err := oidc.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
 	router := Router()
 	service, err := service.New()
 	if err != nil {
@@ -33,6 +50,10 @@ func New() (*Handler, error) {
 	h := &Handler{
 		Router:  router,
 		Service: service,
+		Manager: websocket.NewManager(),
+	}
+	if oidcConfig != nil {
+		h.OIDC = oidc.NewProvider(*oidcConfig)
 	}
 
 	router.PathPrefix("/login/").Handler(
@@ -45,6 +66,10 @@ func New() (*Handler, error) {
 	router.HandleFunc("/signup", h.signUpUserHandler).Methods("POST")
 	router.HandleFunc("/signin", h.signinUserHandler).Methods("POST")
 	router.HandleFunc("/refresh", h.refreshTokenHandler).Methods("POST")
+	router.HandleFunc("/.well-known/jwks.json", h.jwksHandler).Methods("GET")
+	router.HandleFunc("/auth/oidc", h.oidcConfigHandler).Methods("GET")
+	router.HandleFunc("/auth/oidc/login", h.oidcLoginHandler).Methods("GET")
+	router.HandleFunc("/auth/oidc/callback", h.oidcCallbackHandler).Methods("GET")
 
 	router.PathPrefix("/drawboard/").Handler(
 		http.StripPrefix("/drawboard/", http.FileServer(http.Dir("./web/drawboard"))),
@@ -54,11 +79,58 @@ func New() (*Handler, error) {
 		http.Redirect(w, r, "/drawboard/", http.StatusMovedPermanently)
 	}).Methods("GET")
 
-	wsManager := websocket.NewManager()
-	go wsManager.Run()
+	router.PathPrefix("/dashboard/").Handler(
+		http.StripPrefix("/dashboard/", http.FileServer(http.Dir("./web/dashboard"))),
+	).Methods("GET")
+
+	router.HandleFunc("/dashboard", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/dashboard/", http.StatusMovedPermanently)
+	}).Methods("GET")
+
+	authenticated := middleware.AuthMiddleware(h.Service.SessionActive)
+
+	boards := router.PathPrefix("/boards").Subrouter()
+	boards.Use(authenticated)
+	boards.HandleFunc("", h.createBoardHandler).Methods("POST")
+	boards.HandleFunc("", h.listBoardsHandler).Methods("GET")
+	boards.HandleFunc("/import", h.importArchiveHandler).Methods("POST")
+	boards.HandleFunc("/{id}", h.getBoardHandler).Methods("GET")
+	boards.HandleFunc("/{id}", h.updateBoardHandler).Methods("PATCH")
+	boards.HandleFunc("/{id}", h.deleteBoardHandler).Methods("DELETE")
+	boards.HandleFunc("/{id}/history", h.boardHistoryHandler).Methods("GET")
+	boards.HandleFunc("/{id}/export.svg", h.exportSVGHandler).Methods("GET")
+	boards.HandleFunc("/{id}/export.png", h.exportPNGHandler).Methods("GET")
+	boards.HandleFunc("/{id}/export.json", h.exportArchiveHandler).Methods("GET")
+	boards.HandleFunc("/{id}/members", h.listMembersHandler).Methods("GET")
+	boards.HandleFunc("/{id}/members/{user}", h.setMemberHandler).Methods("PUT")
+	boards.HandleFunc("/{id}/members/{user}", h.removeMemberHandler).Methods("DELETE")
+	boards.HandleFunc("/{id}/invites", h.createInviteHandler).Methods("POST")
+	boards.HandleFunc("/{id}/invites", h.listInvitesHandler).Methods("GET")
+	boards.HandleFunc("/{id}/invites/{invite}", h.revokeInviteHandler).Methods("DELETE")
+
+	// no authentication, only boards their owner made public
+	router.HandleFunc("/public/boards/{id}", h.publicBoardHandler).Methods("GET")
+
+	logout := router.PathPrefix("/logout").Subrouter()
+	logout.Use(authenticated)
+	logout.HandleFunc("", h.logoutHandler).Methods("POST")
+	logout.HandleFunc("/all", h.logoutEverywhereHandler).Methods("POST")
+
+	sessions := router.PathPrefix("/sessions").Subrouter()
+	sessions.Use(authenticated)
+	sessions.HandleFunc("", h.listSessionsHandler).Methods("GET")
+	sessions.HandleFunc("/{id}", h.revokeSessionHandler).Methods("DELETE")
+
+	invites := router.PathPrefix("/invites").Subrouter()
+	invites.Use(authenticated)
+	invites.HandleFunc("/redeem", h.redeemInviteHandler).Methods("POST")
+
+	go h.Manager.Run()
+	go h.Service.BatchSaver(batchEventBuffer, BatchSize)
+	go h.Service.Snapshotter(SnapshotInterval, SnapshotMinEvents)
 
 	router.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		h.websocketHandler(w, r, wsManager)
+		h.websocketHandler(w, r, h.Manager)
 	})
 
 	return h, nil
@@ -115,77 +187,110 @@ func (h *Handler) signinUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authExpiryAt := time.Now().Add(15 * time.Minute).Unix()
-	authtoken, err := auth.CreateJWTToken(user.Email, authExpiryAt)
+	authtoken, authExpiryAt, err := h.startSession(w, r, user.Email)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	refreshTokenDTO, err := h.Service.CreateSession(r.Context(), user.Email)
+	response := map[string]interface{}{
+		"message":        "Sign in successful.",
+		"auth-token":     authtoken,
+		"auth-expiry-at": authExpiryAt,
+		"user":           user,
+	}
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// startSession signs the user in on this device. It sets the refresh token,
+// session and user cookies and returns the auth token with its expiry.
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, userID string) (string, int64, error) {
+	refreshTokenDTO, err := h.Service.CreateSession(r.Context(), userID, r.UserAgent(), clientIP(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", 0, err
 	}
 
-	expiresAt, err := strconv.ParseInt(refreshTokenDTO.ExpiresAt, 10, 64)
+	authExpiryAt := time.Now().Add(15 * time.Minute).Unix()
+	authtoken, err := auth.CreateJWTToken(userID, refreshTokenDTO.ID, authExpiryAt)
 	if err != nil {
-		http.Error(w, "Invalid expires at value", http.StatusInternalServerError)
-		return
+		return "", 0, err
 	}
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     "refresh-token",
-		Value:    refreshTokenDTO.TokenHash,
+		Value:    refreshTokenDTO.RefreshToken,
 		Path:     "/refresh",
-		Expires:  time.Unix(expiresAt, 0),
+		Expires:  refreshTokenDTO.ExpiresAt,
 		HttpOnly: true,                    // prevent JS access
 		Secure:   true,                    // send only over HTTPS
 		SameSite: http.SameSiteStrictMode, // CSRF protection
 	})
 
+	// tells the refresh endpoint which of the user's sessions to renew
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session-id",
+		Value:    refreshTokenDTO.ID,
+		Path:     "/refresh",
+		Expires:  refreshTokenDTO.ExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
 	http.SetCookie(w, &http.Cookie{
 		Name:     "user-id",
-		Value:    user.Email,
+		Value:    userID,
 		Path:     "/",
 		HttpOnly: true,                    // prevent JS access
 		Secure:   true,                    // send only over HTTPS
 		SameSite: http.SameSiteStrictMode, // CSRF protection
 	})
 
-	response := map[string]interface{}{
-		"message":        "Sign in successful.",
-		"auth-token":     authtoken,
-		"auth-expiry-at": authExpiryAt,
-		"user":           user,
-	}
-	json.NewEncoder(w).Encode(response)
-
+	return authtoken, authExpiryAt, nil
 }
 
+// refreshTokenHandler trades the refresh token cookie for a new auth token
+// and a new refresh token, everything it needs comes from the cookies
 func (h *Handler) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
-	var req models.RefreshTokenDTO
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
-		return
-	}
-
-	defer r.Body.Close()
-
 	cookie, err := r.Cookie("user-id")
 	if err != nil {
-		http.Error(w, "Error reading cookie", http.StatusInternalServerError)
+		http.Error(w, "Error reading cookie", http.StatusUnauthorized)
+		return
 	}
 
 	userId := cookie.Value
 
 	rtoken, err := r.Cookie("refresh-token")
 	if err != nil {
-		http.Error(w, "error reading token", http.StatusInternalServerError)
+		http.Error(w, "error reading token", http.StatusUnauthorized)
+		return
 	}
 
-	tdto, err := h.Service.UpdateSession(r.Context(), models.RefreshTokenDTO{UserID: userId, RefreshToken: rtoken.Value})
+	sessionCookie, err := r.Cookie("session-id")
 	if err != nil {
+		http.Error(w, "error reading session", http.StatusUnauthorized)
+		return
+	}
+
+	tdto, err := h.Service.UpdateSession(r.Context(), models.RefreshTokenDTO{
+		UserID:       userId,
+		SessionID:    sessionCookie.Value,
+		RefreshToken: rtoken.Value,
+	}, r.UserAgent(), clientIP(r))
+	switch {
+	case errors.Is(err, service.ErrRefreshTokenReused):
+		// someone else holds a copy of the token, sign the session out everywhere
+		h.Manager.DisconnectSession(userId, sessionCookie.Value)
+		clearAuthCookies(w)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case errors.Is(err, service.ErrSessionNotFound), errors.Is(err, service.ErrInvalidRefreshToken):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
 		http.Error(w, "error updating session", http.StatusInternalServerError)
+		return
 	}
 
 	refreshExpiresAt, err := strconv.ParseInt(tdto.RefreshExpriesAt, 10, 64)
@@ -204,6 +309,16 @@ func (h *Handler) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 		SameSite: http.SameSiteStrictMode, // CSRF protection
 	})
 
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session-id",
+		Value:    tdto.SessionID,
+		Path:     "/refresh",
+		Expires:  time.Unix(refreshExpiresAt, 0),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
 	response := map[string]interface{}{
 		"message":        "Sign in successful.",
 		"auth-token":     tdto.AuthToken,
@@ -219,69 +334,170 @@ var upgrader = ws.Upgrader{
 
 const BatchSize = 1000
 
+// boards are compacted every SnapshotInterval once SnapshotMinEvents new events are stored
+const (
+	SnapshotInterval  = time.Minute
+	SnapshotMinEvents = 200
+)
+
+// SendBufferSize is how many outgoing messages a client may lag behind before it is dropped
+const SendBufferSize = 256
+
 var batchEventBuffer = make(chan models.Event, BatchSize)
 
 func (h *Handler) websocketHandler(w http.ResponseWriter, r *http.Request, manager *websocket.Manager) {
 
-	tokenString := r.URL.Query().Get("token")
-	if tokenString == "" {
-		http.Error(w, "Missing token", http.StatusUnauthorized)
+	boardID := r.URL.Query().Get("board")
+	if boardID == "" {
+		http.Error(w, "Missing board", http.StatusBadRequest)
 		return
 	}
 
-	claims, err := auth.VerifyJWTToken(tokenString)
+	// without a token the client may only watch a public board
+	var userId, sessionID string
+	var exp time.Time
+	if tokenString := r.URL.Query().Get("token"); tokenString != "" {
+		claims, err := auth.VerifyJWTToken(tokenString)
+		if err != nil {
+			http.Error(w, "Invalid token: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		mapClaims, ok := claims.(jwt.MapClaims)
+		if !ok {
+			http.Error(w, "Error decoding token", http.StatusInternalServerError)
+			return
+		}
+		userId, _ = mapClaims["sub"].(string)
+		sessionID, _ = mapClaims["sid"].(string)
+
+		// the token outlives a logout, the session does not
+		active, err := h.Service.SessionActive(r.Context(), sessionID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			http.Error(w, "Session has ended", http.StatusUnauthorized)
+			return
+		}
+
+		expTime, err := claims.GetExpirationTime()
+		if err != nil || expTime == nil {
+			http.Error(w, "Invalid token expiry", http.StatusUnauthorized)
+			return
+		}
+		exp = expTime.Time
+	}
+
+	// the owner and members join with their role, anyone else only watches public boards
+	board, err := h.Service.JoinBoard(r.Context(), userId, boardID)
 	if err != nil {
-		http.Error(w, "Invalid token: "+err.Error(), http.StatusUnauthorized)
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+	if board.Archived {
+		http.Error(w, "Board is archived", http.StatusForbidden)
 		return
 	}
 
-	mapClaims, ok := claims.(jwt.MapClaims)
-	if !ok {
-		http.Error(w, "Error decoding token", http.StatusInternalServerError)
+	// a reconnecting client passes the last sequence number it applied
+	var since int64
+	if sinceParam := r.URL.Query().Get("since"); sinceParam != "" {
+		since, err = strconv.ParseInt(sinceParam, 10, 64)
+		if err != nil || since < 0 {
+			http.Error(w, "Invalid since value", http.StatusBadRequest)
+			return
+		}
 	}
 
-	userId := mapClaims["sub"].(string)
+	clientID := helper.GenerateUniqueID()
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	stateMsg, err := h.joinMessage(r.Context(), boardID, clientID, board.Role, since)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	exp, err := claims.GetExpirationTime()
+	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	go func(conn *ws.Conn, exp time.Time) {
-		duration := time.Until(exp)
-		if duration > 0 {
-			time.Sleep(duration)
-		}
-		// Notify client
-		_ = conn.WriteJSON(map[string]string{
-			"type": "TOKEN_EXPIRED",
-		})
-		conn.Close()
-	}(conn, exp.Time)
+	if userId != "" {
+		go func(conn *ws.Conn, exp time.Time) {
+			duration := time.Until(exp)
+			if duration > 0 {
+				time.Sleep(duration)
+			}
+			// Notify client
+			_ = conn.WriteJSON(map[string]string{
+				"type": "TOKEN_EXPIRED",
+			})
+			conn.Close()
+		}(conn, exp)
+	}
+
+	var name string
+	if userId != "" {
+		name = h.Service.DisplayName(r.Context(), userId)
+	}
 
 	client := &websocket.Client{
-		ID:     helper.GenerateUniqueID(),
-		UserID: userId,
-		Conn:   conn,
-		Send:   make(chan []byte),
+		ID:        clientID,
+		UserID:    userId,
+		SessionID: sessionID,
+		Name:      name,
+		Board:     boardID,
+		Role:      board.Role,
+		Conn:      conn,
+		Send:      make(chan []byte, SendBufferSize),
 	}
 
+	// the board goes out before any live event of the room, events committed
+	// until the client is registered are recovered through a sync request
+	client.Send <- stateMsg
 	manager.Register <- client
 
-	go handleRead(client, manager)
+	go h.handleRead(client, manager)
 	go handleWrite(client, manager)
-	go h.Service.BatchSaver(batchEventBuffer, BatchSize)
 }
 
-func handleRead(client *websocket.Client, manager *websocket.Manager) {
+// joinMessage resumes from since when the missed events can be replayed and
+// falls back to the whole board otherwise
+func (h *Handler) joinMessage(ctx context.Context, boardID string, clientID string, role models.BoardRole, since int64) ([]byte, error) {
+	if since > 0 {
+		events, ok, err := h.Service.EventsSince(ctx, boardID, since)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return json.Marshal(models.ServerMessage{
+				Type: models.Resumed,
+				Data: models.ResumedData{ClientID: clientID, Role: role, Events: withoutAuthors(events)},
+			})
+		}
+	}
+	return h.boardStateMessage(ctx, boardID, clientID, role)
+}
+
+// boardStateMessage encodes the authoritative board for the given client
+func (h *Handler) boardStateMessage(ctx context.Context, boardID string, clientID string, role models.BoardRole) ([]byte, error) {
+	objects, seq, err := h.Service.BoardState(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(models.ServerMessage{
+		Type: models.BoardState,
+		Data: models.BoardStateData{Objects: objects, Seq: seq, ClientID: clientID, Role: role},
+	})
+}
+
+func (h *Handler) handleRead(client *websocket.Client, manager *websocket.Manager) {
 	defer func() {
+		h.commitOpenStrokes(client, manager)
 		manager.Unregister <- client
 		client.Conn.Close()
 	}()
@@ -293,18 +509,161 @@ func handleRead(client *websocket.Client, manager *websocket.Manager) {
 			break
 		}
 
+		msgType, err := helper.PeekMessageType(message)
+		if err != nil {
+			logger.Error("Parsing Error: %s", err)
+			continue
+		}
+
+		switch models.MessageType(msgType) {
+		case models.SyncRequest:
+			// only answered to the client itself, viewers need it to catch up
+			h.handleSyncRequest(client, manager, message)
+			continue
+
+		case models.Cursor:
+			// viewers, anonymous ones included, only watch
+			if client.Role.CanEdit() {
+				handleCursor(client, manager, message)
+			}
+			continue
+		}
+
+		// everything else changes the board
+		if !client.Role.CanEdit() {
+			sendError(client, manager, "viewers cannot change the board")
+			continue
+		}
+
+		switch models.MessageType(msgType) {
+		case models.Undo:
+			if _, err := h.Service.Undo(context.Background(), client.Board, client.UserID, client.ID, publisher(client, manager)); err != nil {
+				logger.Error("Undo Error: %s", err)
+			}
+			continue
+
+		case models.Redo:
+			if _, err := h.Service.Redo(context.Background(), client.Board, client.UserID, client.ID, publisher(client, manager)); err != nil {
+				logger.Error("Redo Error: %s", err)
+			}
+			continue
+
+		case models.StrokeBegin, models.StrokeAppend, models.StrokeEnd:
+			h.handleStroke(client, manager, models.MessageType(msgType), message)
+			continue
+		}
+
 		parsedMsg, err := helper.ParseEventData(message)
 		if err != nil {
 			logger.Error("Parsing Error: %s", err)
+			continue
 		}
-		// fmt.Println("msg", parsedMsg)
-		// fmt.Println("err", err)
+		parsedMsg.BoardID = client.Board
+		parsedMsg.UserID = client.UserID
+		parsedMsg.ClientID = client.ID
 
-		batchEventBuffer <- parsedMsg
-		manager.Broadcast <- message
+		_, err = h.Service.CommitEvent(context.Background(), client.Board, parsedMsg, publisher(client, manager))
+		if err != nil {
+			logger.Error("Commit Error: %s", err)
+		}
 	}
 }
 
+// publisher persists committed events and broadcasts them to the client's board
+func publisher(client *websocket.Client, manager *websocket.Manager) func(models.Event) {
+	return func(event models.Event) {
+		// only the stored event keeps its author, see withoutAuthors
+		sent := event
+		sent.UserID = ""
+		data, err := json.Marshal(sent)
+		if err != nil {
+			logger.Error("Encoding Error: %s", err)
+			return
+		}
+		batchEventBuffer <- event
+		manager.Broadcast <- websocket.Message{Board: client.Board, Data: data}
+	}
+}
+
+// withoutAuthors copies the events without their UserID. The author is the
+// user's email and events go to everyone on the board, public viewers included.
+func withoutAuthors(events []models.Event) []models.Event {
+	sent := make([]models.Event, len(events))
+	for i, event := range events {
+		event.UserID = ""
+		sent[i] = event
+	}
+	return sent
+}
+
+// handleCursor relays the pointer of the client to the rest of the board,
+// only its latest position of each tick goes out and cursors are never stored
+func handleCursor(client *websocket.Client, manager *websocket.Manager, message []byte) {
+	if !client.AllowEphemeral(time.Now()) {
+		return
+	}
+
+	cursor, err := helper.ParseCursor(message)
+	if err != nil {
+		logger.Error("Parsing Error: %s", err)
+		return
+	}
+	cursor.ClientID = client.ID
+
+	data, err := json.Marshal(models.ServerMessage{Type: models.Cursor, Data: cursor})
+	if err != nil {
+		logger.Error("Encoding Error: %s", err)
+		return
+	}
+	manager.Ephemeral <- websocket.Message{Board: client.Board, Except: client.ID, Key: "cursor:" + client.ID, Data: data}
+}
+
+// sendError tells only this client why its message was dropped
+func sendError(client *websocket.Client, manager *websocket.Manager, message string) {
+	data, err := json.Marshal(models.ServerMessage{
+		Type: models.Error,
+		Data: models.ErrorData{Message: message},
+	})
+	if err != nil {
+		logger.Error("Encoding Error: %s", err)
+		return
+	}
+	manager.Broadcast <- websocket.Message{Board: client.Board, To: client.ID, Data: data}
+}
+
+// handleSyncRequest sends the client the events it missed, or the whole board
+// when they are no longer kept in memory
+func (h *Handler) handleSyncRequest(client *websocket.Client, manager *websocket.Manager, message []byte) {
+	req, err := helper.ParseSyncRequest(message)
+	if err != nil {
+		logger.Error("Parsing Error: %s", err)
+		return
+	}
+
+	ctx := context.Background()
+	events, ok, err := h.Service.EventsSince(ctx, client.Board, req.Since)
+	if err != nil {
+		logger.Error("Sync Error: %s", err)
+		return
+	}
+
+	var data []byte
+	if ok {
+		data, err = json.Marshal(models.ServerMessage{
+			Type: models.SyncEvents,
+			Data: models.SyncEventsData{Events: withoutAuthors(events)},
+		})
+	} else {
+		data, err = h.boardStateMessage(ctx, client.Board, client.ID, client.Role)
+	}
+	if err != nil {
+		logger.Error("Encoding Error: %s", err)
+		return
+	}
+
+	manager.Broadcast <- websocket.Message{Board: client.Board, To: client.ID, Data: data}
+}
+
 func handleWrite(client *websocket.Client, _ *websocket.Manager) {
 	for message := range client.Send {
 		err := client.Conn.WriteMessage(ws.TextMessage, message)
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/shared-drawboard/internal/middleware"
+	"github.com/shared-drawboard/internal/models"
+)
+
+// createInviteHandler returns a new invite with its token, the only time the token is shown
+func (h *Handler) createInviteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	var req models.InviteCreateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+
+	invite, err := h.Service.CreateInvite(r.Context(), userID, mux.Vars(r)["id"], req)
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
+
+func (h *Handler) listInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	invites, err := h.Service.ListInvites(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"invites": invites})
+}
+
+func (h *Handler) revokeInviteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	vars := mux.Vars(r)
+
+	if err := h.Service.RevokeInvite(r.Context(), userID, vars["id"], vars["invite"]); err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// redeemInviteHandler adds the caller to the invite's board and returns the board
+func (h *Handler) redeemInviteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	var req models.InviteRedeemDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+
+	board, err := h.Service.RedeemInvite(r.Context(), userID, req.Token)
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	// open connections may still carry a lower role
+	h.Manager.Disconnect(board.ID, userID)
+
+	json.NewEncoder(w).Encode(board)
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/shared-drawboard/pkg/auth"
+)
+
+// jwksHandler publishes the public keys auth tokens are signed with, so
+// other services can verify them without sharing a secret
+func (h *Handler) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := auth.PublicKeys()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(keys)
+}
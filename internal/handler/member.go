@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/shared-drawboard/internal/middleware"
+	"github.com/shared-drawboard/internal/models"
+)
+
+func (h *Handler) listMembersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	members, err := h.Service.ListMembers(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"members": members})
+}
+
+// setMemberHandler shares the board with a user or changes their role,
+// body {"role": "editor"|"viewer"}
+func (h *Handler) setMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	vars := mux.Vars(r)
+
+	var req models.BoardMember
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+
+	member, err := h.Service.SetMember(r.Context(), userID, vars["id"], vars["user"], req.Role)
+	if err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	// open connections still carry the old role
+	h.Manager.Disconnect(vars["id"], vars["user"])
+
+	json.NewEncoder(w).Encode(member)
+}
+
+func (h *Handler) removeMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	vars := mux.Vars(r)
+
+	if err := h.Service.RemoveMember(r.Context(), userID, vars["id"], vars["user"]); err != nil {
+		http.Error(w, err.Error(), boardErrorStatus(err))
+		return
+	}
+
+	h.Manager.Disconnect(vars["id"], vars["user"])
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shared-drawboard/pkg/logger"
+	"github.com/shared-drawboard/pkg/oidc"
+)
+
+const (
+	// oidcStateCookie holds the state, nonce and PKCE verifier of a login in progress
+	oidcStateCookie = "oidc-state"
+	// OIDCLoginTimeout is how long the user has to sign in at the provider
+	OIDCLoginTimeout = 10 * time.Minute
+)
+
+// oidcConfigHandler tells the login page whether to offer single sign-on
+func (h *Handler) oidcConfigHandler(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"enabled": h.OIDC != nil})
+}
+
+// oidcLoginHandler sends the browser to the identity provider
+func (h *Handler) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if h.OIDC == nil {
+		http.Error(w, "Single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.NewState()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := h.OIDC.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		logger.Error("OIDC login failed: %v", err)
+		http.Error(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	// Lax, the callback is a navigation from the provider's site
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    strings.Join(values[:], "."),
+		Path:     "/auth/oidc",
+		MaxAge:   int(OIDCLoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// oidcCallbackHandler finishes the login the provider sent the browser back
+// from. The user with the provider's email is signed in like with a password,
+// and created on their first login.
+func (h *Handler) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if h.OIDC == nil {
+		http.Error(w, "Single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	// a login state is only good for one callback
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     "/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if err != nil {
+		ssoFailed(w, r, "Sign in took too long, try again")
+		return
+	}
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		logger.Error("OIDC login failed: %s %s", providerErr, query.Get("error_description"))
+		ssoFailed(w, r, "Sign in was cancelled or denied")
+		return
+	}
+
+	values := strings.Split(cookie.Value, ".")
+	state := query.Get("state")
+	if len(values) != 3 || state == "" || subtle.ConstantTimeCompare([]byte(values[0]), []byte(state)) != 1 {
+		ssoFailed(w, r, "Sign in could not be verified, try again")
+		return
+	}
+	nonce, verifier := values[1], values[2]
+
+	identity, err := h.OIDC.Exchange(r.Context(), query.Get("code"), verifier, nonce)
+	if errors.Is(err, oidc.ErrEmailMissing) || errors.Is(err, oidc.ErrEmailUnverified) {
+		ssoFailed(w, r, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("OIDC login failed: %v", err)
+		ssoFailed(w, r, "Sign in could not be verified, try again")
+		return
+	}
+
+	user, err := h.Service.SSOUser(r.Context(), identity.Email, identity.Name)
+	if err != nil {
+		logger.Error("OIDC login failed: %v", err)
+		ssoFailed(w, r, "Sign in failed, try again")
+		return
+	}
+
+	authtoken, authExpiryAt, err := h.startSession(w, r, user.Email)
+	if err != nil {
+		logger.Error("OIDC login failed: %v", err)
+		ssoFailed(w, r, "Sign in failed, try again")
+		return
+	}
+
+	// the fragment never reaches a server, the login page stores the token
+	fragment := url.Values{
+		"auth-token":     {authtoken},
+		"auth-expiry-at": {strconv.FormatInt(authExpiryAt, 10)},
+	}
+	http.Redirect(w, r, "/login/#"+fragment.Encode(), http.StatusFound)
+}
+
+// ssoFailed sends the browser back to the login page, which shows the message
+func ssoFailed(w http.ResponseWriter, r *http.Request, message string) {
+	fragment := url.Values{"sso-error": {message}}
+	http.Redirect(w, r, "/login/#"+fragment.Encode(), http.StatusFound)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/shared-drawboard/internal/middleware"
+	"github.com/shared-drawboard/internal/service"
+)
+
+func (h *Handler) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	sessions, err := h.Service.ListSessions(r.Context(), userID, middleware.SessionIDFromContext(r.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"sessions": sessions})
+}
+
+// revokeSessionHandler signs one device out and closes its websockets,
+// revoking the current session also clears its cookies
+func (h *Handler) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	sessionID := mux.Vars(r)["id"]
+
+	if err := h.Service.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.Manager.DisconnectSession(userID, sessionID)
+	if sessionID == middleware.SessionIDFromContext(r.Context()) {
+		clearAuthCookies(w)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logoutHandler ends the session of the auth token and closes the websockets
+// opened with it, other devices stay signed in
+func (h *Handler) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	sessionID := middleware.SessionIDFromContext(r.Context())
+
+	if err := h.Service.Logout(r.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if sessionID == "" {
+		h.Manager.DisconnectUser(userID)
+	} else {
+		h.Manager.DisconnectSession(userID, sessionID)
+	}
+	clearAuthCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logoutEverywhereHandler ends every session of the user and closes all of
+// their websockets
+func (h *Handler) logoutEverywhereHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+
+	if err := h.Service.LogoutEverywhere(r.Context(), userID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.Manager.DisconnectUser(userID)
+	clearAuthCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clearAuthCookies expires the cookies set by signing in, they must match
+// the paths they were set with
+func clearAuthCookies(w http.ResponseWriter) {
+	for name, path := range map[string]string{"refresh-token": "/refresh", "session-id": "/refresh", "user-id": "/"} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     path,
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+}
+
+// clientIP is the address the request came from, as recorded on sessions
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
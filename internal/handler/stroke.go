@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/shared-drawboard/internal/models"
+	"github.com/shared-drawboard/internal/websocket"
+	"github.com/shared-drawboard/pkg/helper"
+	"github.com/shared-drawboard/pkg/logger"
+)
+
+const (
+	// MaxOpenStrokes is how many strokes a client may draw at the same time
+	MaxOpenStrokes = 4
+	// MaxStrokePoints is how many points a streamed stroke may collect
+	MaxStrokePoints = 20000
+)
+
+// handleStroke collects the points of a streamed freehand stroke and relays
+// them to the rest of the board. The stroke is committed as a single
+// FreehandDraw event when it ends.
+func (h *Handler) handleStroke(client *websocket.Client, manager *websocket.Manager, msgType models.MessageType, message []byte) {
+	data, err := helper.ParseStroke(message)
+	if err != nil {
+		logger.Error("Parsing Error: %s", err)
+		return
+	}
+
+	if msgType == models.StrokeBegin {
+		if client.Strokes == nil {
+			client.Strokes = make(map[string]*websocket.Stroke)
+		}
+		if _, ok := client.Strokes[data.ID]; ok {
+			sendError(client, manager, "stroke has already begun")
+			return
+		}
+		if len(client.Strokes) >= MaxOpenStrokes {
+			sendError(client, manager, "too many strokes in progress")
+			return
+		}
+		client.Strokes[data.ID] = &websocket.Stroke{
+			Tool: data.Tool,
+			Data: models.FreehandDrawData{ID: data.ID, Color: data.Color, Thickness: data.Thickness},
+		}
+	}
+
+	stroke, ok := client.Strokes[data.ID]
+	if !ok {
+		sendError(client, manager, "unknown stroke")
+		return
+	}
+	if len(stroke.Data.Points)+len(data.Points) > MaxStrokePoints {
+		sendError(client, manager, "stroke has too many points")
+		return
+	}
+	stroke.Data.Points = append(stroke.Data.Points, data.Points...)
+
+	switch msgType {
+	case models.StrokeEnd:
+		delete(client.Strokes, data.ID)
+		h.commitStroke(client, manager, stroke)
+	case models.StrokeBegin:
+		relayStroke(client, manager, msgType, stroke)
+	default:
+		// points that are not relayed now go out with a later append
+		if client.AllowEphemeral(time.Now()) {
+			relayStroke(client, manager, msgType, stroke)
+		}
+	}
+}
+
+// relayStroke sends the rest of the board the points of the stroke it has
+// not seen yet, a begin also carries how the stroke looks
+func relayStroke(client *websocket.Client, manager *websocket.Manager, msgType models.MessageType, stroke *websocket.Stroke) {
+	data := models.StrokeData{
+		ID:       stroke.Data.ID,
+		ClientID: client.ID,
+		Points:   stroke.Data.Points[stroke.Relayed:],
+	}
+	if msgType == models.StrokeBegin {
+		data.Tool = stroke.Tool
+		data.Color = stroke.Data.Color
+		data.Thickness = stroke.Data.Thickness
+	}
+
+	payload, err := json.Marshal(models.ServerMessage{Type: msgType, Data: data})
+	if err != nil {
+		logger.Error("Encoding Error: %s", err)
+		return
+	}
+
+	// every piece has its own key so none of them replaces another
+	key := "stroke:" + client.ID + ":" + stroke.Data.ID + ":" + strconv.Itoa(stroke.Relayed)
+	stroke.Relayed = len(stroke.Data.Points)
+	manager.Ephemeral <- websocket.Message{Board: client.Board, Except: client.ID, Key: key, Data: payload}
+}
+
+// commitStroke stores the stroke as one FreehandDraw event with the stroke's
+// id. A stroke without a line to draw is dropped and the others are told to
+// forget it.
+func (h *Handler) commitStroke(client *websocket.Client, manager *websocket.Manager, stroke *websocket.Stroke) {
+	if len(stroke.Data.Points) < 2 {
+		stroke.Data.Points = nil
+		stroke.Relayed = 0
+		relayStroke(client, manager, models.StrokeEnd, stroke)
+		return
+	}
+
+	event := models.Event{
+		BoardID:  client.Board,
+		UserID:   client.UserID,
+		ClientID: client.ID,
+		Type:     models.FreehandDraw,
+		Tool:     stroke.Tool,
+		Data:     stroke.Data,
+	}
+	if _, err := h.Service.CommitEvent(context.Background(), client.Board, event, publisher(client, manager)); err != nil {
+		logger.Error("Commit Error: %s", err)
+	}
+}
+
+// commitOpenStrokes keeps what was drawn of the strokes a client was still
+// drawing when it disconnected
+func (h *Handler) commitOpenStrokes(client *websocket.Client, manager *websocket.Manager) {
+	for id, stroke := range client.Strokes {
+		delete(client.Strokes, id)
+		h.commitStroke(client, manager, stroke)
+	}
+}
@@ -2,28 +2,28 @@ package middleware
 
 import (
 	"context"
-	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
-	"github.com/shared-drawboard/pkg/logger"
+	"github.com/shared-drawboard/pkg/auth"
 )
 
-func setEnvVariables() {
-	if err := godotenv.Load(); err != nil {
-		logger.Error("Error loading environment variables: %s", err)
-		os.Exit(1)
+type contextKey string
+
+// SessionChecker reports whether the session an auth token was issued for is still active
+type SessionChecker func(ctx context.Context, sessionID string) (bool, error)
+
+// AuthMiddleware accepts requests with a valid auth token whose session has
+// not ended, so a logout also ends the API access of the tokens issued to it
+func AuthMiddleware(sessionActive SessionChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authenticate(next, sessionActive)
 	}
 }
 
-type contextKey string
-
-func AuthMiddleware(next http.Handler) http.Handler {
+func authenticate(next http.Handler, sessionActive SessionChecker) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		setEnvVariables()
 		//get auth header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -38,31 +38,53 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		secretKey := os.Getenv("SECRETKEY_FOR_JWT")
-
-		//parse and validate JWT
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(secretKey), nil
-		})
-
-		if err != nil || !token.Valid {
+		//parse and validate JWT with the same keys it was signed with
+		verified, err := auth.VerifyJWTToken(tokenStr)
+		if err != nil {
 			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
 
 		// Extract claims (e.g., user ID)
-		claims, ok := token.Claims.(jwt.MapClaims)
+		claims, ok := verified.(jwt.MapClaims)
 		if !ok {
 			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
 			return
 		}
-		userID := claims["sub"].(string)
+		userID, ok := claims["sub"].(string)
+		if !ok || userID == "" {
+			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+			return
+		}
+		sessionID, _ := claims["sid"].(string)
+
+		// the token outlives a logout, the session does not
+		active, err := sessionActive(r.Context(), sessionID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			http.Error(w, "Session has ended", http.StatusUnauthorized)
+			return
+		}
 
 		// Add to context
 		ctx := context.WithValue(r.Context(), contextKey("user_id"), userID)
+		ctx = context.WithValue(ctx, contextKey("session_id"), sessionID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// UserIDFromContext returns the JWT subject stored by AuthMiddleware
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(contextKey("user_id")).(string)
+	return userID, ok
+}
+
+// SessionIDFromContext returns the session the auth token was issued for,
+// tokens issued before sessions were tracked have none
+func SessionIDFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(contextKey("session_id")).(string)
+	return sessionID
+}
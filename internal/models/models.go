@@ -1,5 +1,10 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // currently using as DTO but its better to keep models and DTOs seperate
 type User struct {
 	ID       string `json:"_id,omitempty" bson:"_id,omitempty"`
@@ -9,12 +14,93 @@ type User struct {
 }
 
 type SessionDTO struct {
-	ID         string `json:"_id,omitempty" bson:"_id,omitempty"`
-	UserID     string `json:"user_id" bson:"user_id"`
-	TokenHash  string `json:"token_hash" bson:"token_hash"`
-	ExpiresAt  string `json:"expires_at" bson:"expires_at"`
-	CreatedAt  string `json:"created_at" bson:"created_at"`
-	LastUsedAt string `json:"last_used_at" bson:"last_used_at"`
+	ID         string    `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID     string    `json:"user_id" bson:"user_id"`
+	TokenHash  string    `json:"token_hash" bson:"token_hash"`
+	UserAgent  string    `json:"user_agent" bson:"user_agent"`
+	IP         string    `json:"ip" bson:"ip"`
+	ExpiresAt  time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt  string    `json:"created_at" bson:"created_at"`
+	LastUsedAt string    `json:"last_used_at" bson:"last_used_at"`
+	// RefreshToken is only known when the session is created, then only its hash is kept
+	RefreshToken string `json:"-" bson:"-"`
+}
+
+// SessionInfo is a signed in device as its user sees it, Current marks the
+// session of the auth token that asked
+type SessionInfo struct {
+	ID         string `json:"_id"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+	Current    bool   `json:"current"`
+}
+
+type Board struct {
+	ID        string `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name      string `json:"name" bson:"name"`
+	OwnerID   string `json:"owner_id" bson:"owner_id"`
+	Archived  bool   `json:"archived" bson:"archived"`
+	Public    bool   `json:"public" bson:"public"` // watchable without an account
+	CreatedAt string `json:"created_at" bson:"created_at"`
+	UpdatedAt string `json:"updated_at" bson:"updated_at"`
+	// Role of the requesting user, only set in responses
+	Role BoardRole `json:"role,omitempty" bson:"-"`
+}
+
+// BoardRole is what a user may do on a board. The owner is the board's
+// OwnerID, editors and viewers are stored as board members.
+type BoardRole string
+
+const (
+	RoleOwner  BoardRole = "owner"
+	RoleEditor BoardRole = "editor"
+	RoleViewer BoardRole = "viewer"
+)
+
+// CanEdit reports whether the role may draw on the board
+func (r BoardRole) CanEdit() bool {
+	return r == RoleOwner || r == RoleEditor
+}
+
+// BoardMember grants a user other than the owner access to a board
+type BoardMember struct {
+	BoardID   string    `json:"board_id" bson:"board_id"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	Role      BoardRole `json:"role" bson:"role"`
+	CreatedAt string    `json:"created_at" bson:"created_at"`
+}
+
+// Invite is a revocable link that makes whoever redeems it a member of the
+// board with Role. Token is only returned once, when the invite is created.
+type Invite struct {
+	ID        string    `json:"_id,omitempty" bson:"_id,omitempty"`
+	BoardID   string    `json:"board_id" bson:"board_id"`
+	Role      BoardRole `json:"role" bson:"role"`
+	CreatedBy string    `json:"created_by" bson:"created_by"`
+	CreatedAt string    `json:"created_at" bson:"created_at"`
+	ExpiresAt string    `json:"expires_at" bson:"expires_at"`
+	Uses      int64     `json:"uses" bson:"uses"`
+	Token     string    `json:"token,omitempty" bson:"-"`
+}
+
+// InviteCreateDTO asks for an invite granting Role that expires after ExpiresIn seconds
+type InviteCreateDTO struct {
+	Role      BoardRole `json:"role"`
+	ExpiresIn int64     `json:"expires_in,omitempty"`
+}
+
+type InviteRedeemDTO struct {
+	Token string `json:"token"`
+}
+
+// BoardUpdateDTO carries the optional fields of a board PATCH request
+type BoardUpdateDTO struct {
+	Name     *string `json:"name,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+	Public   *bool   `json:"public,omitempty"`
 }
 
 type EventType string
@@ -24,24 +110,44 @@ const (
 	ShapeCreate  EventType = "shapeCreate"
 	TextAdd      EventType = "textAdd"
 	ObjectDelete EventType = "objectDelete"
+	ObjectUpdate EventType = "objectUpdate"
 	BoardClear   EventType = "boardClear"
 )
 
+// EventAction marks events the server generated on behalf of a user
+type EventAction string
+
+const (
+	ActionUndo EventAction = "undo"
+	ActionRedo EventAction = "redo"
+)
+
+// Event is stamped by the server with its board, author, connection and
+// commit time (Timestamp, unix milliseconds) before it is stored
 type Event struct {
+	BoardID   string      `json:"board_id,omitempty" bson:"board_id"`
+	Seq       int64       `json:"seq,omitempty" bson:"seq"`
+	UserID    string      `json:"user_id,omitempty" bson:"user_id"`
+	ClientID  string      `json:"client_id,omitempty" bson:"client_id"`
+	Action    EventAction `json:"action,omitempty" bson:"action,omitempty"`
 	Type      EventType   `json:"type" bson:"type"`
 	Tool      string      `json:"tool" bson:"tool"`
 	CreatedAt string      `json:"timestamp,omitempty" bson:"created_at,omitempty"`
+	Timestamp int64       `json:"ts,omitempty" bson:"ts"`
 	Data      interface{} `json:"data" bson:"data"`
 }
 
 // Event Data Structures
+// Drawables carry a client generated ID that later events use to refer to them
 type FreehandDrawData struct {
+	ID        string  `json:"id" bson:"id"`
 	Color     string  `json:"color" bson:"color"`
 	Thickness float64 `json:"thickness" bson:"thickness"`
 	Points    []Point `json:"points" bson:"points"`
 }
 
 type ShapeCreateData struct {
+	ID        string  `json:"id" bson:"id"`
 	Color     string  `json:"color" bson:"color"`
 	Thickness float64 `json:"thickness" bson:"thickness"`
 	X         float64 `json:"x" bson:"x"`
@@ -51,6 +157,7 @@ type ShapeCreateData struct {
 }
 
 type TextAddData struct {
+	ID        string  `json:"id" bson:"id"`
 	Color     string  `json:"color" bson:"color"`
 	Thickness float64 `json:"thickness" bson:"thickness"`
 	X         float64 `json:"x" bson:"x"`
@@ -59,7 +166,84 @@ type TextAddData struct {
 }
 
 type ObjectDeleteData struct {
-	Index int `json:"index" bson:"index"`
+	ID string `json:"id" bson:"id"`
+	// Index is the list position deletes addressed the object by before
+	// drawables had ids, only events stored back then carry it
+	Index *int `json:"index,omitempty" bson:"index,omitempty"`
+}
+
+// ObjectUpdateData edits an existing drawable, nil fields are left untouched.
+// DX/DY move any object, X/Y/Width/Height set the box of shapes and text.
+type ObjectUpdateData struct {
+	ID        string   `json:"id" bson:"id"`
+	DX        float64  `json:"dx,omitempty" bson:"dx,omitempty"`
+	DY        float64  `json:"dy,omitempty" bson:"dy,omitempty"`
+	X         *float64 `json:"x,omitempty" bson:"x,omitempty"`
+	Y         *float64 `json:"y,omitempty" bson:"y,omitempty"`
+	Width     *float64 `json:"width,omitempty" bson:"width,omitempty"`
+	Height    *float64 `json:"height,omitempty" bson:"height,omitempty"`
+	Color     *string  `json:"color,omitempty" bson:"color,omitempty"`
+	Thickness *float64 `json:"thickness,omitempty" bson:"thickness,omitempty"`
+	Text      *string  `json:"text,omitempty" bson:"text,omitempty"`
+}
+
+// BoardObject is a drawable materialized from a board's event log
+type BoardObject struct {
+	ID        string  `json:"id" bson:"id"`
+	Type      string  `json:"type" bson:"type"`
+	Color     string  `json:"color" bson:"color"`
+	Thickness float64 `json:"thickness" bson:"thickness"`
+	Points    []Point `json:"points,omitempty" bson:"points,omitempty"`
+	X         float64 `json:"x" bson:"x"`
+	Y         float64 `json:"y" bson:"y"`
+	Width     float64 `json:"width" bson:"width"`
+	Height    float64 `json:"height" bson:"height"`
+	Text      string  `json:"text,omitempty" bson:"text,omitempty"`
+}
+
+// Snapshot is the compacted object list of a board up to LastEventID
+type Snapshot struct {
+	ID          string        `json:"_id,omitempty" bson:"_id,omitempty"`
+	BoardID     string        `json:"board_id" bson:"board_id"`
+	Objects     []BoardObject `json:"objects" bson:"objects"`
+	LastEventID string        `json:"last_event_id" bson:"last_event_id"`
+	LastSeq     int64         `json:"last_seq" bson:"last_seq"`
+	CreatedAt   string        `json:"created_at" bson:"created_at"`
+}
+
+// BoardHistoryDTO is a board as it was right after the event with sequence number Seq
+type BoardHistoryDTO struct {
+	BoardID   string        `json:"board_id"`
+	Seq       int64         `json:"seq"`
+	Timestamp int64         `json:"ts,omitempty"`
+	Objects   []BoardObject `json:"objects"`
+}
+
+// PublicBoardDTO is what anyone may see of a public board
+type PublicBoardDTO struct {
+	ID      string        `json:"_id"`
+	Name    string        `json:"name"`
+	Seq     int64         `json:"seq"`
+	Objects []BoardObject `json:"objects"`
+}
+
+// ArchiveVersion is the schema version written into board archives
+const ArchiveVersion = 1
+
+// BoardArchive is a portable copy of a board. It holds either the whole
+// event log or a snapshot of the objects, or a snapshot followed by events.
+type BoardArchive struct {
+	Version    int              `json:"version"`
+	ExportedAt string           `json:"exported_at"`
+	Board      Board            `json:"board"`
+	Snapshot   *ArchiveSnapshot `json:"snapshot,omitempty"`
+	Events     []Event          `json:"events,omitempty"`
+}
+
+// ArchiveSnapshot is the board's objects right after the event with sequence number Seq
+type ArchiveSnapshot struct {
+	Seq     int64         `json:"seq"`
+	Objects []BoardObject `json:"objects"`
 }
 
 type Point struct {
@@ -67,8 +251,109 @@ type Point struct {
 	Y float64 `json:"y" bson:"y"`
 }
 
+// MessageType identifies websocket payloads that are generated by the server
+type MessageType string
+
+const (
+	BoardState  MessageType = "boardState"
+	SyncRequest MessageType = "syncRequest"
+	SyncEvents  MessageType = "syncEvents"
+	Resumed     MessageType = "resumed"
+	Undo        MessageType = "undo"
+	Redo        MessageType = "redo"
+	Error       MessageType = "error"
+
+	// presence is broadcast live and never stored
+	PresenceJoin   MessageType = "presenceJoin"
+	PresenceLeave  MessageType = "presenceLeave"
+	PresenceRoster MessageType = "presenceRoster"
+	Cursor         MessageType = "cursor"
+
+	// freehand strokes are streamed while they are drawn and stored as
+	// a single FreehandDraw event when they end
+	StrokeBegin  MessageType = "strokeBegin"
+	StrokeAppend MessageType = "strokeAppend"
+	StrokeEnd    MessageType = "strokeEnd"
+
+	// Batch carries the ephemeral messages of a room merged over one tick
+	Batch MessageType = "batch"
+)
+
+type ServerMessage struct {
+	Type MessageType `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// BoardStateData is the whole board as of Seq, ClientID tells the receiver which events
+// it sent itself and Role what it may do on the board
+type BoardStateData struct {
+	Objects  []BoardObject `json:"objects"`
+	Seq      int64         `json:"seq"`
+	ClientID string        `json:"client_id,omitempty"`
+	Role     BoardRole     `json:"role,omitempty"`
+}
+
+// SyncRequestData asks for every event after the last sequence number the client applied
+type SyncRequestData struct {
+	Since int64 `json:"since"`
+}
+
+type SyncEventsData struct {
+	Events []Event `json:"events"`
+}
+
+// ResumedData answers a reconnect with the events missed since the client's last sequence number
+type ResumedData struct {
+	ClientID string    `json:"client_id"`
+	Role     BoardRole `json:"role"`
+	Events   []Event   `json:"events"`
+}
+
+// PresenceData is one connection on a board. It goes to everyone on the
+// board, public viewers included, so it never carries the user's email.
+type PresenceData struct {
+	ClientID  string    `json:"client_id"`
+	Name      string    `json:"name,omitempty"`
+	Anonymous bool      `json:"anonymous,omitempty"`
+	Role      BoardRole `json:"role"`
+}
+
+// RosterData lists everyone on the board, it is sent to a client when it joins
+type RosterData struct {
+	Clients []PresenceData `json:"clients"`
+}
+
+// CursorData is a pointer position in board coordinates, the server tags it with the sender
+type CursorData struct {
+	ClientID string  `json:"client_id,omitempty"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+}
+
+// StrokeData is a piece of a stroke in progress. Color, Thickness and Tool
+// come with its begin, every message may carry the points drawn since the last.
+type StrokeData struct {
+	ID        string  `json:"id"`
+	ClientID  string  `json:"client_id,omitempty"`
+	Tool      string  `json:"tool,omitempty"`
+	Color     string  `json:"color,omitempty"`
+	Thickness float64 `json:"thickness,omitempty"`
+	Points    []Point `json:"points,omitempty"`
+}
+
+// BatchData holds whole server messages in the order they were sent
+type BatchData struct {
+	Messages []json.RawMessage `json:"messages"`
+}
+
+// ErrorData tells a client why its message was rejected
+type ErrorData struct {
+	Message string `json:"message"`
+}
+
 type RefreshTokenDTO struct {
 	UserID           string `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	SessionID        string `json:"session_id,omitempty" bson:"session_id,omitempty"`
 	AuthToken        string `json:"auth-token,omitempty" bson:"auth-token,omitempty"`
 	AuthExpiresAt    string `json:"auth-token-expiry,omitempty" bson:"auth-token-expiry,omitempty"`
 	RefreshToken     string `json:"refresh-token,omitempty" bson:"refresh-token,omitempty"`
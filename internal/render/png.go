@@ -0,0 +1,374 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shared-drawboard/internal/models"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// MaxDimension caps the width and height of a raster export
+const MaxDimension = 4096
+
+// maxCircleSegments caps the polygon a circle is drawn with, the radius
+// grows with the scale the client asks for
+const maxCircleSegments = 256
+
+var namedColors = map[string]color.NRGBA{
+	"black":       {0, 0, 0, 255},
+	"white":       {255, 255, 255, 255},
+	"red":         {255, 0, 0, 255},
+	"green":       {0, 128, 0, 255},
+	"blue":        {0, 0, 255, 255},
+	"yellow":      {255, 255, 0, 255},
+	"orange":      {255, 165, 0, 255},
+	"purple":      {128, 0, 128, 255},
+	"gray":        {128, 128, 128, 255},
+	"grey":        {128, 128, 128, 255},
+	"transparent": {},
+}
+
+var (
+	fontOnce sync.Once
+	fontData *opentype.Font
+	fontErr  error
+)
+
+// PNG rasterizes the board objects the same way SVG lays them out
+func PNG(objects []models.BoardObject, opts Options) ([]byte, error) {
+	if opts.Width > MaxDimension || opts.Height > MaxDimension {
+		return nil, ErrInvalidOptions
+	}
+	background := color.NRGBA{}
+	if opts.Background != "" {
+		var ok bool
+		if background, ok = parseColor(opts.Background); !ok {
+			return nil, ErrInvalidOptions
+		}
+	}
+
+	area, width, height := opts.viewport(objects)
+	if width > MaxDimension || height > MaxDimension {
+		shrink := math.Min(float64(MaxDimension)/float64(width), float64(MaxDimension)/float64(height))
+		width = max(int(float64(width)*shrink), 1)
+		height = max(int(float64(height)*shrink), 1)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	// fit the area into the image keeping its aspect ratio and center it,
+	// like the default preserveAspectRatio of the SVG export
+	scale := math.Min(float64(width)/area.Width, float64(height)/area.Height)
+	r := &rasterizer{
+		img:   img,
+		z:     vector.NewRasterizer(width, height),
+		scale: scale,
+		dx:    (float64(width)-area.Width*scale)/2 - area.X*scale,
+		dy:    (float64(height)-area.Height*scale)/2 - area.Y*scale,
+	}
+
+	for _, obj := range objects {
+		if err := r.drawObject(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type rasterizer struct {
+	img    *image.RGBA
+	z      *vector.Rasterizer
+	scale  float64
+	dx, dy float64
+}
+
+// point converts board coordinates to pixels
+func (r *rasterizer) point(x, y float64) (float64, float64) {
+	return x*r.scale + r.dx, y*r.scale + r.dy
+}
+
+func (r *rasterizer) drawObject(obj models.BoardObject) error {
+	c, ok := parseColor(obj.Color)
+	if !ok {
+		c = namedColors["black"]
+	}
+	half := math.Max(obj.Thickness*r.scale, 1) / 2
+
+	bounds := r.img.Bounds()
+	r.z.Reset(bounds.Dx(), bounds.Dy())
+
+	switch obj.Type {
+	case "draw", "erase":
+		if len(obj.Points) < 2 {
+			return nil
+		}
+		points := make([][2]float64, len(obj.Points))
+		for i, p := range obj.Points {
+			points[i][0], points[i][1] = r.point(p.X, p.Y)
+		}
+		r.strokePolyline(points, half)
+
+	case "line":
+		x1, y1 := r.point(obj.X, obj.Y)
+		x2, y2 := r.point(obj.X+obj.Width, obj.Y+obj.Height)
+		r.strokePolyline([][2]float64{{x1, y1}, {x2, y2}}, half)
+
+	case "rectangle":
+		x1, y1 := r.point(obj.X, obj.Y)
+		x2, y2 := r.point(obj.X+obj.Width, obj.Y+obj.Height)
+		r.strokePolyline([][2]float64{{x1, y1}, {x2, y1}, {x2, y2}, {x1, y2}, {x1, y1}}, half)
+
+	case "circle":
+		cx, cy, radius := circleGeometry(obj)
+		cx, cy = r.point(cx, cy)
+		radius *= r.scale
+		if !r.visible(cx-radius-half, cy-radius-half, cx+radius+half, cy+radius+half) {
+			return nil
+		}
+		r.fillPolygon(circle(cx, cy, radius+half), false)
+		if radius > half {
+			r.fillPolygon(circle(cx, cy, radius-half), true)
+		}
+
+	case "text":
+		return r.drawText(obj, c)
+
+	default:
+		return nil
+	}
+
+	r.z.Draw(r.img, bounds, image.NewUniform(c), image.Point{})
+	return nil
+}
+
+// strokePolyline fills each segment and a disc on every point, which gives
+// the round caps and joins the drawboard client uses
+func (r *rasterizer) strokePolyline(points [][2]float64, half float64) {
+	for i, p := range points {
+		if r.covered(p[0], p[1], half) {
+			// nothing drawn after this could show
+			bounds := r.img.Bounds()
+			w, h := float64(bounds.Dx()), float64(bounds.Dy())
+			r.fillPolygon([][2]float64{{0, 0}, {w, 0}, {w, h}, {0, h}}, false)
+			return
+		}
+		if r.visible(p[0]-half, p[1]-half, p[0]+half, p[1]+half) {
+			r.fillPolygon(circle(p[0], p[1], half), false)
+		}
+		if i == 0 {
+			continue
+		}
+		q := points[i-1]
+		if !r.visible(math.Min(p[0], q[0])-half, math.Min(p[1], q[1])-half, math.Max(p[0], q[0])+half, math.Max(p[1], q[1])+half) {
+			continue
+		}
+		dx, dy := p[0]-q[0], p[1]-q[1]
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		nx, ny := -dy/length*half, dx/length*half
+		r.fillPolygon([][2]float64{
+			{q[0] + nx, q[1] + ny},
+			{p[0] + nx, p[1] + ny},
+			{p[0] - nx, p[1] - ny},
+			{q[0] - nx, q[1] - ny},
+		}, false)
+	}
+}
+
+// visible reports whether the box in pixels overlaps the image, geometry
+// outside of it is skipped before it is built
+func (r *rasterizer) visible(minX, minY, maxX, maxY float64) bool {
+	bounds := r.img.Bounds()
+	return maxX >= 0 && maxY >= 0 && minX <= float64(bounds.Dx()) && minY <= float64(bounds.Dy())
+}
+
+// covered reports whether the disc covers the whole image, with a margin
+// for the polygon that approximates it
+func (r *rasterizer) covered(cx, cy, radius float64) bool {
+	bounds := r.img.Bounds()
+	farX := math.Max(math.Abs(cx), math.Abs(cx-float64(bounds.Dx())))
+	farY := math.Max(math.Abs(cy), math.Abs(cy-float64(bounds.Dy())))
+	return math.Hypot(farX, farY) < radius*math.Cos(math.Pi/maxCircleSegments)
+}
+
+// fillPolygon adds a closed polygon to the coverage mask. Polygons are
+// always wound the same way so overlaps merge, a hole winds the other way
+// to cut out what was filled before. The polygon is clipped to the image
+// first, the rasterizer is slow with edges far outside of it.
+func (r *rasterizer) fillPolygon(points [][2]float64, hole bool) {
+	points = r.clip(points)
+	if len(points) < 3 {
+		return
+	}
+	area := 0.0
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	reverse := (area < 0) != hole
+
+	at := func(i int) (float32, float32) {
+		if reverse {
+			i = len(points) - 1 - i
+		}
+		return float32(points[i][0]), float32(points[i][1])
+	}
+	r.z.MoveTo(at(0))
+	for i := 1; i < len(points); i++ {
+		r.z.LineTo(at(i))
+	}
+	r.z.ClosePath()
+}
+
+// clip cuts the polygon to the image plus a pixel on every side, one edge
+// of the image at a time. Clipping to a convex area keeps the winding.
+func (r *rasterizer) clip(points [][2]float64) [][2]float64 {
+	bounds := r.img.Bounds()
+	minX, minY := -1.0, -1.0
+	maxX, maxY := float64(bounds.Dx())+1, float64(bounds.Dy())+1
+
+	edges := []struct {
+		inside func(p [2]float64) bool
+		cross  func(p, q [2]float64) [2]float64
+	}{
+		{func(p [2]float64) bool { return p[0] >= minX }, func(p, q [2]float64) [2]float64 { return atX(p, q, minX) }},
+		{func(p [2]float64) bool { return p[0] <= maxX }, func(p, q [2]float64) [2]float64 { return atX(p, q, maxX) }},
+		{func(p [2]float64) bool { return p[1] >= minY }, func(p, q [2]float64) [2]float64 { return atY(p, q, minY) }},
+		{func(p [2]float64) bool { return p[1] <= maxY }, func(p, q [2]float64) [2]float64 { return atY(p, q, maxY) }},
+	}
+	for _, edge := range edges {
+		if len(points) == 0 {
+			return nil
+		}
+		clipped := make([][2]float64, 0, len(points)+4)
+		prev := points[len(points)-1]
+		for _, p := range points {
+			switch {
+			case edge.inside(p) && edge.inside(prev):
+				clipped = append(clipped, p)
+			case edge.inside(p):
+				clipped = append(clipped, edge.cross(prev, p), p)
+			case edge.inside(prev):
+				clipped = append(clipped, edge.cross(prev, p))
+			}
+			prev = p
+		}
+		points = clipped
+	}
+	return points
+}
+
+// atX is where the segment from p to q crosses the vertical line at x
+func atX(p, q [2]float64, x float64) [2]float64 {
+	t := (x - p[0]) / (q[0] - p[0])
+	return [2]float64{x, p[1] + t*(q[1]-p[1])}
+}
+
+// atY is where the segment from p to q crosses the horizontal line at y
+func atY(p, q [2]float64, y float64) [2]float64 {
+	t := (y - p[1]) / (q[1] - p[1])
+	return [2]float64{p[0] + t*(q[0]-p[0]), y}
+}
+
+func (r *rasterizer) drawText(obj models.BoardObject, c color.NRGBA) error {
+	size := fontSize(obj) * r.scale
+	if obj.Text == "" || size <= 0 {
+		return nil
+	}
+	// glyphs are rasterized whole, one taller than twice the image is skipped
+	bounds := r.img.Bounds()
+	if size > float64(2*max(bounds.Dx(), bounds.Dy())) {
+		return nil
+	}
+	x, y := r.point(obj.X, obj.Y)
+	if !r.visible(x, y-size, x+size*float64(len(obj.Text)), y+size) {
+		return nil
+	}
+
+	fontOnce.Do(func() {
+		fontData, fontErr = opentype.Parse(goregular.TTF)
+	})
+	if fontErr != nil {
+		return fontErr
+	}
+	face, err := opentype.NewFace(fontData, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+
+	d := font.Drawer{
+		Dst:  r.img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)},
+	}
+	d.DrawString(obj.Text)
+	return nil
+}
+
+// circle approximates a circle with a polygon fine enough at this size
+func circle(cx, cy, radius float64) [][2]float64 {
+	n := maxCircleSegments
+	if segments := math.Ceil(2 * math.Pi * radius / 2); segments < maxCircleSegments {
+		n = max(int(segments), 12)
+	}
+	points := make([][2]float64, n)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		points[i] = [2]float64{cx + radius*math.Cos(angle), cy + radius*math.Sin(angle)}
+	}
+	return points
+}
+
+// parseColor understands #rgb, #rgba, #rrggbb, #rrggbbaa and a few names
+func parseColor(s string) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, true
+	}
+	if !strings.HasPrefix(s, "#") {
+		return color.NRGBA{}, false
+	}
+
+	hex := s[1:]
+	if len(hex) == 3 || len(hex) == 4 {
+		var long strings.Builder
+		for _, ch := range hex {
+			long.WriteRune(ch)
+			long.WriteRune(ch)
+		}
+		hex = long.String()
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.NRGBA{}, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, false
+	}
+	return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+}
@@ -0,0 +1,147 @@
+package render
+
+import (
+	"errors"
+	"math"
+	"regexp"
+
+	"github.com/shared-drawboard/internal/models"
+)
+
+// Padding is the margin kept around the drawing when no crop is given
+const Padding = 20
+
+// a crop is between MinCropSize and MaxCropSize board units wide and high,
+// and starts no further than MaxCropOffset from the origin
+const (
+	MinCropSize   = 1
+	MaxCropSize   = 1 << 20
+	MaxCropOffset = 1 << 24
+)
+
+var ErrInvalidOptions = errors.New("width, height and crop must be positive and in range and background a hex or named color")
+
+// Rect is an area of the board in board coordinates
+type Rect struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// Options control the area and size of an export. A zero Crop exports the
+// bounds of the drawing, a zero Width or Height follows the crop size.
+type Options struct {
+	Width      int
+	Height     int
+	Background string
+	Crop       Rect
+}
+
+var colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]{1,20})$`)
+
+func (o Options) Validate() error {
+	if o.Width < 0 || o.Height < 0 {
+		return ErrInvalidOptions
+	}
+	if o.Crop != (Rect{}) && !o.Crop.valid() {
+		return ErrInvalidOptions
+	}
+	if o.Background != "" && !colorPattern.MatchString(o.Background) {
+		return ErrInvalidOptions
+	}
+	return nil
+}
+
+// valid reports whether a crop is finite and small enough that the scale
+// of the export stays bounded
+func (c Rect) valid() bool {
+	for _, v := range []float64{c.X, c.Y, c.Width, c.Height} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return math.Abs(c.X) <= MaxCropOffset && math.Abs(c.Y) <= MaxCropOffset &&
+		c.Width >= MinCropSize && c.Width <= MaxCropSize &&
+		c.Height >= MinCropSize && c.Height <= MaxCropSize
+}
+
+// viewport returns the board area to render and the output size in pixels
+func (o Options) viewport(objects []models.BoardObject) (Rect, int, int) {
+	area := o.Crop
+	if area.Width == 0 || area.Height == 0 {
+		area = Bounds(objects)
+	}
+
+	width, height := o.Width, o.Height
+	switch {
+	case width == 0 && height == 0:
+		width, height = pixels(area.Width), pixels(area.Height)
+	case width == 0:
+		width = pixels(float64(height) * area.Width / area.Height)
+	case height == 0:
+		height = pixels(float64(width) * area.Height / area.Width)
+	}
+	return area, max(width, 1), max(height, 1)
+}
+
+// pixels rounds a size up to whole pixels, a drawing too large to convert
+// gets MaxCropSize and is scaled down by the raster export
+func pixels(size float64) int {
+	if !(size < MaxCropSize) {
+		return MaxCropSize
+	}
+	return int(math.Ceil(size))
+}
+
+// radius and center of a circle, drawn like the drawboard client does
+func circleGeometry(obj models.BoardObject) (float64, float64, float64) {
+	r := math.Sqrt(obj.Width*obj.Width+obj.Height*obj.Height) / 2
+	return obj.X + obj.Width/2, obj.Y + obj.Height/2, r
+}
+
+// fontSize matches the drawboard client, which sizes text by thickness
+func fontSize(obj models.BoardObject) float64 {
+	return obj.Thickness * 10
+}
+
+// Bounds returns the area covered by the objects plus Padding on every side
+func Bounds(objects []models.BoardObject) Rect {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	extend := func(x, y, margin float64) {
+		minX, minY = math.Min(minX, x-margin), math.Min(minY, y-margin)
+		maxX, maxY = math.Max(maxX, x+margin), math.Max(maxY, y+margin)
+	}
+
+	for _, obj := range objects {
+		half := obj.Thickness / 2
+		switch obj.Type {
+		case "draw", "erase":
+			for _, p := range obj.Points {
+				extend(p.X, p.Y, half)
+			}
+		case "circle":
+			cx, cy, r := circleGeometry(obj)
+			extend(cx, cy, r+half)
+		case "text":
+			size := fontSize(obj)
+			extend(obj.X, obj.Y-size, 0)
+			// rough width, the exact one depends on the font
+			extend(obj.X+float64(len(obj.Text))*size*0.6, obj.Y+size*0.25, 0)
+		default:
+			extend(obj.X, obj.Y, half)
+			extend(obj.X+obj.Width, obj.Y+obj.Height, half)
+		}
+	}
+
+	if math.IsInf(minX, 1) {
+		return Rect{Width: 800, Height: 600}
+	}
+	return Rect{
+		X:      minX - Padding,
+		Y:      minY - Padding,
+		Width:  maxX - minX + 2*Padding,
+		Height: maxY - minY + 2*Padding,
+	}
+}
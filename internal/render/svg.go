@@ -0,0 +1,92 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/shared-drawboard/internal/models"
+)
+
+// SVG renders the board objects as a standalone SVG document
+func SVG(objects []models.BoardObject, opts Options) []byte {
+	area, width, height := opts.viewport(objects)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="%s %s %s %s">`+"\n",
+		width, height, num(area.X), num(area.Y), num(area.Width), num(area.Height))
+
+	if opts.Background != "" {
+		fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+			num(area.X), num(area.Y), num(area.Width), num(area.Height), attr(opts.Background))
+	}
+
+	for _, obj := range objects {
+		writeSVGObject(&buf, obj)
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
+
+func writeSVGObject(buf *bytes.Buffer, obj models.BoardObject) {
+	stroke := fmt.Sprintf(`fill="none" stroke="%s" stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"`,
+		attr(obj.Color), num(obj.Thickness))
+
+	switch obj.Type {
+	case "draw", "erase":
+		if len(obj.Points) < 2 {
+			return
+		}
+		buf.WriteString(`<polyline points="`)
+		for i, p := range obj.Points {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(num(p.X) + "," + num(p.Y))
+		}
+		fmt.Fprintf(buf, `" %s/>`+"\n", stroke)
+
+	case "line":
+		fmt.Fprintf(buf, `<line x1="%s" y1="%s" x2="%s" y2="%s" %s/>`+"\n",
+			num(obj.X), num(obj.Y), num(obj.X+obj.Width), num(obj.Y+obj.Height), stroke)
+
+	case "rectangle":
+		x, w := obj.X, obj.Width
+		if w < 0 {
+			x, w = x+w, -w
+		}
+		y, h := obj.Y, obj.Height
+		if h < 0 {
+			y, h = y+h, -h
+		}
+		fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" %s/>`+"\n",
+			num(x), num(y), num(w), num(h), stroke)
+
+	case "circle":
+		cx, cy, r := circleGeometry(obj)
+		fmt.Fprintf(buf, `<circle cx="%s" cy="%s" r="%s" %s/>`+"\n", num(cx), num(cy), num(r), stroke)
+
+	case "text":
+		fmt.Fprintf(buf, `<text x="%s" y="%s" fill="%s" font-family="Arial, sans-serif" font-size="%s">`,
+			num(obj.X), num(obj.Y), attr(obj.Color), num(fontSize(obj)))
+		xml.EscapeText(buf, []byte(obj.Text))
+		buf.WriteString("</text>\n")
+	}
+}
+
+func num(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "0"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func attr(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
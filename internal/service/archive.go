@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/shared-drawboard/internal/models"
+	"github.com/shared-drawboard/pkg/helper"
+	"github.com/shared-drawboard/pkg/logger"
+)
+
+var (
+	ErrInvalidArchive     = errors.New("invalid board archive")
+	ErrUnsupportedArchive = errors.New("unsupported board archive version")
+)
+
+// noEventID is the cursor of an imported snapshot, every imported event
+// is stored after it
+const noEventID = "000000000000000000000000"
+
+// ExportBoard returns the archive of a board userID owns or is a member of
+func (s *Service) ExportBoard(ctx context.Context, userID string, boardID string, snapshotOnly bool) (*models.BoardArchive, error) {
+	if _, err := s.GetBoard(ctx, userID, boardID); err != nil {
+		return nil, err
+	}
+	return s.ArchiveBoard(ctx, boardID, snapshotOnly)
+}
+
+// ArchiveBoard builds the archive of a board without any ownership check.
+// It holds the whole event log, or only the current objects with snapshotOnly.
+func (s *Service) ArchiveBoard(ctx context.Context, boardID string, snapshotOnly bool) (*models.BoardArchive, error) {
+	board, err := s.FindBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &models.BoardArchive{
+		Version:    models.ArchiveVersion,
+		ExportedAt: strconv.FormatInt(time.Now().Unix(), 10),
+		Board:      *board,
+	}
+
+	if snapshotOnly {
+		objects, seq, err := s.BoardState(ctx, boardID)
+		if err != nil {
+			return nil, err
+		}
+		archive.Snapshot = &models.ArchiveSnapshot{Seq: seq, Objects: objects}
+		return archive, nil
+	}
+
+	unsaved := s.unsavedEvents(boardID)
+	stored, err := s.DB.FindEvents(ctx, boardID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.Event, 0, len(stored))
+	var lastSeq int64
+	for _, e := range stored {
+		event, err := toEventModel(e)
+		if err != nil {
+			logger.Error("Skipping stored event: %s", err)
+			continue
+		}
+		events = append(events, event)
+		lastSeq = max(lastSeq, event.Seq)
+	}
+	for _, event := range unsaved {
+		if event.Seq > lastSeq {
+			events = append(events, event)
+		}
+	}
+
+	// events stored before sequencing have seq 0 and stay in front
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	archive.Events = events
+	return archive, nil
+}
+
+// ParseArchive decodes an archive and the data of each of its events
+func ParseArchive(data []byte) (*models.BoardArchive, error) {
+	var raw struct {
+		Version    int                     `json:"version"`
+		ExportedAt string                  `json:"exported_at"`
+		Board      models.Board            `json:"board"`
+		Snapshot   *models.ArchiveSnapshot `json:"snapshot"`
+		Events     []json.RawMessage       `json:"events"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+	if raw.Version < 1 || raw.Version > models.ArchiveVersion {
+		return nil, ErrUnsupportedArchive
+	}
+
+	archive := &models.BoardArchive{
+		Version:    raw.Version,
+		ExportedAt: raw.ExportedAt,
+		Board:      raw.Board,
+		Snapshot:   raw.Snapshot,
+		Events:     make([]models.Event, 0, len(raw.Events)),
+	}
+
+	for i, rawEvent := range raw.Events {
+		var event models.Event
+		if err := json.Unmarshal(rawEvent, &event); err != nil {
+			return nil, fmt.Errorf("%w: event %d: %s", ErrInvalidArchive, i, err)
+		}
+		parse := helper.ParseEventData
+		if event.Seq == 0 {
+			// stored before sequencing, and maybe before drawables had ids
+			parse = helper.ParseLegacyEventData
+		}
+		parsed, err := parse(rawEvent)
+		if err != nil {
+			return nil, fmt.Errorf("%w: event %d: %s", ErrInvalidArchive, i, err)
+		}
+		event.Data = parsed.Data
+		archive.Events = append(archive.Events, event)
+	}
+
+	return archive, nil
+}
+
+// validateArchive checks that the sequence numbers of the events follow the
+// snapshot and each other, events stored before sequencing may come first
+func validateArchive(archive *models.BoardArchive) error {
+	var lastSeq int64
+	if archive.Snapshot != nil {
+		if archive.Snapshot.Seq < 0 {
+			return fmt.Errorf("%w: negative snapshot seq", ErrInvalidArchive)
+		}
+		for _, obj := range archive.Snapshot.Objects {
+			// objects drawn before ids were introduced have none
+			if obj.ID == "" {
+				continue
+			}
+			if err := helper.ValidateObjectID(obj.ID); err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+			}
+		}
+		lastSeq = archive.Snapshot.Seq
+	}
+
+	for i, event := range archive.Events {
+		if event.Seq == 0 && lastSeq == 0 {
+			continue
+		}
+		if event.Seq <= lastSeq {
+			return fmt.Errorf("%w: event %d is out of sequence", ErrInvalidArchive, i)
+		}
+		lastSeq = event.Seq
+	}
+	return nil
+}
+
+// ImportBoard stores the archive as a new board owned by ownerID, keeping
+// the sequence numbers of its events
+func (s *Service) ImportBoard(ctx context.Context, ownerID string, archive *models.BoardArchive) (*models.Board, error) {
+	name, err := validateBoardName(archive.Board.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateArchive(archive); err != nil {
+		return nil, err
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	board := models.Board{
+		Name:      name,
+		OwnerID:   ownerID,
+		Archived:  archive.Board.Archived,
+		CreatedAt: archive.Board.CreatedAt,
+		UpdatedAt: now,
+	}
+	if board.CreatedAt == "" {
+		board.CreatedAt = now
+	}
+
+	board.ID, err = s.DB.CreateBoard(ctx, board)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.importBoardData(ctx, board.ID, archive, now); err != nil {
+		if cleanupErr := s.DB.DeleteBoard(ctx, board.ID); cleanupErr != nil {
+			logger.Error("Cleanup of partially imported board %s failed: %s", board.ID, cleanupErr)
+		}
+		return nil, err
+	}
+
+	if len(archive.Events) > 0 {
+		s.markDirty(board.ID)
+	}
+	return &board, nil
+}
+
+func (s *Service) importBoardData(ctx context.Context, boardID string, archive *models.BoardArchive, now string) error {
+	if archive.Snapshot != nil {
+		_, err := s.DB.SaveSnapshot(ctx, models.Snapshot{
+			BoardID:     boardID,
+			Objects:     archive.Snapshot.Objects,
+			LastEventID: noEventID,
+			LastSeq:     archive.Snapshot.Seq,
+			CreatedAt:   now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(archive.Events) == 0 {
+		return nil
+	}
+
+	batch := make([]interface{}, 0, len(archive.Events))
+	for _, event := range archive.Events {
+		event.BoardID = boardID
+		batch = append(batch, event)
+	}
+	return s.DB.BatchSave(ctx, batch)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/shared-drawboard/internal/models"
+)
+
+// legacyBoard stores a board whose log starts with events from before
+// drawables had ids and before events were sequenced
+func legacyBoard(t *testing.T, db *memoryDB) string {
+	t.Helper()
+	ctx := context.Background()
+
+	boardID, err := db.CreateBoard(ctx, models.Board{Name: "Legacy board", OwnerID: "owner@example.com", CreatedAt: "1700000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points := []map[string]float64{{"x": 1, "y": 1}, {"x": 5, "y": 8}}
+	events := []struct {
+		seq       int64
+		eventType models.EventType
+		tool      string
+		data      interface{}
+	}{
+		// no ids, deletes addressed objects by index
+		{0, models.FreehandDraw, "pen", map[string]interface{}{"color": "#000", "thickness": 2, "points": points}},
+		{0, models.ShapeCreate, "rectangle", map[string]interface{}{"color": "red", "thickness": 1, "x": 10, "y": 10, "width": 20, "height": 5}},
+		{0, models.ShapeCreate, "line", map[string]interface{}{"color": "red", "thickness": 1, "x": 0, "y": 0, "width": 9, "height": 9}},
+		{0, models.ObjectDelete, "", map[string]interface{}{"index": 1}},
+		// no object at the index, nothing is deleted
+		{0, models.ObjectDelete, "", map[string]interface{}{"index": 7}},
+		// ids, not sequenced yet
+		{0, models.TextAdd, "text", map[string]interface{}{"id": "t1", "color": "blue", "thickness": 1, "x": 3, "y": 4, "text": "hi"}},
+		{0, models.ShapeCreate, "circle", map[string]interface{}{"id": "c1", "color": "green", "thickness": 3, "x": 0, "y": 0, "width": 6, "height": 6}},
+		// sequenced
+		{1, models.ObjectUpdate, "", map[string]interface{}{"id": "t1", "dx": 2, "dy": 2}},
+		{2, models.FreehandDraw, "pen", map[string]interface{}{"id": "f1", "color": "#123", "thickness": 4, "points": points}},
+		{3, models.ObjectDelete, "", map[string]interface{}{"id": "c1"}},
+	}
+	for _, e := range events {
+		if err := db.storeRawEvent(boardID, e.seq, e.eventType, e.tool, e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return boardID
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	tests := []struct {
+		name         string
+		snapshotOnly bool
+	}{
+		{"event log", false},
+		{"snapshot", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newMemoryDB()
+			s := &Service{DB: db}
+			sourceID := legacyBoard(t, db)
+
+			archive, err := s.ArchiveBoard(ctx, sourceID, tt.snapshotOnly)
+			if err != nil {
+				t.Fatalf("ArchiveBoard: %v", err)
+			}
+			data, err := json.Marshal(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := ParseArchive(data)
+			if err != nil {
+				t.Fatalf("ParseArchive: %v", err)
+			}
+			board, err := s.ImportBoard(ctx, "importer@example.com", parsed)
+			if err != nil {
+				t.Fatalf("ImportBoard: %v", err)
+			}
+
+			wantObjects, wantSeq, err := s.BoardState(ctx, sourceID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotObjects, gotSeq, err := s.BoardState(ctx, board.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the legacy delete removed the rectangle at index 1
+			var types []string
+			for _, obj := range wantObjects {
+				types = append(types, obj.Type)
+			}
+			if got, want := strings.Join(types, ","), "pen,line,text,pen"; got != want {
+				t.Fatalf("source board has objects %s, want %s", got, want)
+			}
+			// compared as JSON, an empty point list may come back as nil
+			got, _ := json.Marshal(gotObjects)
+			want, _ := json.Marshal(wantObjects)
+			if string(got) != string(want) {
+				t.Errorf("imported objects = %s, want %s", got, want)
+			}
+			if gotSeq != wantSeq {
+				t.Errorf("imported seq = %d, want %d", gotSeq, wantSeq)
+			}
+		})
+	}
+}
+
+func TestParseArchiveRequiresIDsOnSequencedEvents(t *testing.T) {
+	data := []byte(`{"version": 1, "board": {"name": "b"}, "events": [
+		{"seq": 1, "type": "shapeCreate", "tool": "rectangle", "data": {"x": 1, "y": 1, "width": 2, "height": 2}}
+	]}`)
+	if _, err := ParseArchive(data); err == nil {
+		t.Fatal("ParseArchive accepted a sequenced event without an object id")
+	}
+}
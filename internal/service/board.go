@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shared-drawboard/internal/database"
+	"github.com/shared-drawboard/internal/models"
+)
+
+var (
+	ErrBoardNotFound    = errors.New("board not found")
+	ErrBoardForbidden   = errors.New("not allowed to access this board")
+	ErrInvalidBoardName = errors.New("board name must be between 1 and 100 characters")
+)
+
+const maxBoardNameLength = 100
+
+func toBoardModel(b *database.Board) models.Board {
+	return models.Board{
+		ID:        b.ID.Hex(),
+		Name:      b.Name,
+		OwnerID:   b.OwnerID,
+		Archived:  b.Archived,
+		Public:    b.Public,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+}
+
+func validateBoardName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxBoardNameLength {
+		return "", ErrInvalidBoardName
+	}
+	return name, nil
+}
+
+func (s *Service) CreateBoard(ctx context.Context, ownerID string, name string) (*models.Board, error) {
+	name, err := validateBoardName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	board := models.Board{
+		Name:      name,
+		OwnerID:   ownerID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	board.ID, err = s.DB.CreateBoard(ctx, board)
+	if err != nil {
+		return nil, err
+	}
+	board.Role = models.RoleOwner
+	return &board, nil
+}
+
+// ListBoards returns the boards owned by userID followed by the ones shared with them
+func (s *Service) ListBoards(ctx context.Context, userID string, includeArchived bool) ([]models.Board, error) {
+	owned, err := s.DB.FindBoardsByOwner(ctx, userID, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.DB.FindMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[string]models.BoardRole, len(memberships))
+	ids := make([]string, 0, len(memberships))
+	for _, m := range memberships {
+		roles[m.BoardID] = models.BoardRole(m.Role)
+		ids = append(ids, m.BoardID)
+	}
+
+	shared, err := s.DB.FindBoardsByIDs(ctx, ids, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.Board, 0, len(owned)+len(shared))
+	for i := range owned {
+		b := toBoardModel(&owned[i])
+		b.Role = models.RoleOwner
+		res = append(res, b)
+	}
+	for i := range shared {
+		b := toBoardModel(&shared[i])
+		b.Role = roles[b.ID]
+		res = append(res, b)
+	}
+	return res, nil
+}
+
+// FindBoard looks a board up without any ownership check
+func (s *Service) FindBoard(ctx context.Context, boardID string) (*models.Board, error) {
+	board, err := s.DB.FindBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	if board == nil {
+		return nil, ErrBoardNotFound
+	}
+	b := toBoardModel(board)
+	return &b, nil
+}
+
+// GetBoard returns the board if userID owns it or is one of its members,
+// with Role set to what the user may do on it
+func (s *Service) GetBoard(ctx context.Context, userID string, boardID string) (*models.Board, error) {
+	board, err := s.FindBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	if board.OwnerID == userID {
+		board.Role = models.RoleOwner
+		return board, nil
+	}
+
+	member, err := s.DB.FindBoardMember(ctx, boardID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrBoardForbidden
+	}
+	board.Role = models.BoardRole(member.Role)
+	return board, nil
+}
+
+// ownBoard returns the board only if it is owned by userID
+func (s *Service) ownBoard(ctx context.Context, userID string, boardID string) (*models.Board, error) {
+	board, err := s.FindBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	if board.OwnerID != userID {
+		return nil, ErrBoardForbidden
+	}
+	board.Role = models.RoleOwner
+	return board, nil
+}
+
+func (s *Service) UpdateBoard(ctx context.Context, userID string, boardID string, update models.BoardUpdateDTO) (*models.Board, error) {
+	if _, err := s.ownBoard(ctx, userID, boardID); err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		name, err := validateBoardName(*update.Name)
+		if err != nil {
+			return nil, err
+		}
+		update.Name = &name
+	}
+
+	if err := s.DB.UpdateBoard(ctx, boardID, update); err != nil {
+		return nil, err
+	}
+	return s.ownBoard(ctx, userID, boardID)
+}
+
+// DeleteBoard removes the board and everything drawn on it. disconnect
+// closes the board's connections first, so no client commits to the board
+// while it is dropped from memory and its events are deleted.
+func (s *Service) DeleteBoard(ctx context.Context, userID string, boardID string, disconnect func()) error {
+	if _, err := s.ownBoard(ctx, userID, boardID); err != nil {
+		return err
+	}
+	disconnect()
+	s.dropLiveBoard(boardID)
+	s.dropPendingEvents(boardID)
+	return s.DB.DeleteBoard(ctx, boardID)
+}
+
+// ReadBoardObjects returns the board and its current objects if the user may read it
+func (s *Service) ReadBoardObjects(ctx context.Context, userID string, boardID string) (*models.Board, []models.BoardObject, error) {
+	board, err := s.GetBoard(ctx, userID, boardID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	objects, _, err := s.BoardState(ctx, boardID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return board, objects, nil
+}
+
+// PublicBoard returns the board with Role viewer if it is public and not
+// archived, any other board is reported as not found
+func (s *Service) PublicBoard(ctx context.Context, boardID string) (*models.Board, error) {
+	board, err := s.FindBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	if !board.Public || board.Archived {
+		return nil, ErrBoardNotFound
+	}
+	board.Role = models.RoleViewer
+	return board, nil
+}
+
+// JoinBoard resolves the role a client joins the board with. Anyone may
+// watch a public board, anonymous clients have no userID.
+func (s *Service) JoinBoard(ctx context.Context, userID string, boardID string) (*models.Board, error) {
+	if userID != "" {
+		board, err := s.GetBoard(ctx, userID, boardID)
+		if !errors.Is(err, ErrBoardForbidden) {
+			return board, err
+		}
+	}
+	return s.PublicBoard(ctx, boardID)
+}
+
+// PublicBoardState returns the current objects of a public board
+func (s *Service) PublicBoardState(ctx context.Context, boardID string) (*models.PublicBoardDTO, error) {
+	board, err := s.PublicBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, seq, err := s.BoardState(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PublicBoardDTO{ID: board.ID, Name: board.Name, Seq: seq, Objects: objects}, nil
+}
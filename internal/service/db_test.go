@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/shared-drawboard/internal/database"
+	"github.com/shared-drawboard/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryDB keeps what the tests store in maps. Methods a test does not
+// need are left to the embedded interface and panic when called.
+type memoryDB struct {
+	database.DB
+
+	boards    map[string]database.Board
+	events    []database.Event
+	snapshots []database.Snapshot
+	sessions  map[string]database.Session
+
+	// beforeRotate runs at the start of RotateSession, a test uses it to let
+	// another refresh win the race
+	beforeRotate func()
+	// beforeSave runs at the start of BatchSave, saveErr fails it
+	beforeSave func()
+	saveErr    error
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{
+		boards:   make(map[string]database.Board),
+		sessions: make(map[string]database.Session),
+	}
+}
+
+func (m *memoryDB) CreateBoard(ctx context.Context, b models.Board) (string, error) {
+	id := primitive.NewObjectID()
+	m.boards[id.Hex()] = database.Board{
+		ID:        id,
+		Name:      b.Name,
+		OwnerID:   b.OwnerID,
+		Archived:  b.Archived,
+		Public:    b.Public,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+	return id.Hex(), nil
+}
+
+func (m *memoryDB) FindBoard(ctx context.Context, id string) (*database.Board, error) {
+	board, ok := m.boards[id]
+	if !ok {
+		return nil, nil
+	}
+	return &board, nil
+}
+
+func (m *memoryDB) DeleteBoard(ctx context.Context, id string) error {
+	delete(m.boards, id)
+	return nil
+}
+
+func (m *memoryDB) CreateSession(ctx context.Context, s models.SessionDTO) (string, error) {
+	id := primitive.NewObjectID()
+	m.sessions[id.Hex()] = database.Session{
+		ID:         id,
+		UserID:     s.UserID,
+		TokenHash:  s.TokenHash,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		ExpiresAt:  s.ExpiresAt,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+	}
+	return id.Hex(), nil
+}
+
+// RotateSession only replaces the hash while the session still holds oldHash, like the Mongo filter
+func (m *memoryDB) RotateSession(ctx context.Context, s models.SessionDTO, oldHash string) (bool, error) {
+	if hook := m.beforeRotate; hook != nil {
+		m.beforeRotate = nil
+		hook()
+	}
+
+	session, ok := m.sessions[s.ID]
+	if !ok || session.UserID != s.UserID || session.TokenHash != oldHash {
+		return false, nil
+	}
+	session.TokenHash = s.TokenHash
+	session.UserAgent = s.UserAgent
+	session.IP = s.IP
+	session.LastUsedAt = strconv.FormatInt(time.Now().Unix(), 10)
+	session.ExpiresAt = time.Now().Add(24 * 7 * time.Hour)
+	session.RotatedHashes = append(session.RotatedHashes, oldHash)
+	if len(session.RotatedHashes) > database.MaxRotatedHashes {
+		session.RotatedHashes = session.RotatedHashes[len(session.RotatedHashes)-database.MaxRotatedHashes:]
+	}
+	m.sessions[s.ID] = session
+	return true, nil
+}
+
+func (m *memoryDB) FindSession(ctx context.Context, id string) (*database.Session, error) {
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (m *memoryDB) DeleteSession(ctx context.Context, id string) (bool, error) {
+	_, ok := m.sessions[id]
+	delete(m.sessions, id)
+	return ok, nil
+}
+
+// BatchSave stores models.Event values the way Mongo would, with a new _id
+// and the data kept as raw BSON
+func (m *memoryDB) BatchSave(ctx context.Context, batch []interface{}) error {
+	if m.beforeSave != nil {
+		m.beforeSave()
+	}
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	for _, doc := range batch {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		var event database.Event
+		if err := bson.Unmarshal(raw, &event); err != nil {
+			return err
+		}
+		event.ID = primitive.NewObjectID()
+		m.events = append(m.events, event)
+	}
+	return nil
+}
+
+func (m *memoryDB) FindEvents(ctx context.Context, boardID string, afterID string) ([]database.Event, error) {
+	var events []database.Event
+	for _, event := range m.events {
+		if event.BoardID == boardID && event.ID.Hex() > afterID {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID.Hex() < events[j].ID.Hex() })
+	return events, nil
+}
+
+func (m *memoryDB) SaveSnapshot(ctx context.Context, snap models.Snapshot) (string, error) {
+	lastEventID, err := primitive.ObjectIDFromHex(snap.LastEventID)
+	if err != nil {
+		return "", err
+	}
+	id := primitive.NewObjectID()
+	m.snapshots = append(m.snapshots, database.Snapshot{
+		ID:          id,
+		BoardID:     snap.BoardID,
+		Objects:     snap.Objects,
+		LastEventID: lastEventID,
+		LastSeq:     snap.LastSeq,
+		CreatedAt:   snap.CreatedAt,
+	})
+	return id.Hex(), nil
+}
+
+func (m *memoryDB) FindLatestSnapshot(ctx context.Context, boardID string) (*database.Snapshot, error) {
+	for i := len(m.snapshots) - 1; i >= 0; i-- {
+		if m.snapshots[i].BoardID == boardID {
+			snap := m.snapshots[i]
+			return &snap, nil
+		}
+	}
+	return nil, nil
+}
+
+// storeRawEvent adds an event as an older version of the server stored it,
+// data is any value that marshals to the stored payload
+func (m *memoryDB) storeRawEvent(boardID string, seq int64, eventType models.EventType, tool string, data interface{}) error {
+	t, value, err := bson.MarshalValue(data)
+	if err != nil {
+		return err
+	}
+	m.events = append(m.events, database.Event{
+		ID:      primitive.NewObjectID(),
+		BoardID: boardID,
+		Seq:     seq,
+		Type:    string(eventType),
+		Tool:    tool,
+		Data:    bson.RawValue{Type: t, Value: value},
+	})
+	return nil
+}
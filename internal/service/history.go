@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shared-drawboard/internal/canvas"
+	"github.com/shared-drawboard/internal/models"
+	"github.com/shared-drawboard/pkg/logger"
+)
+
+var ErrInvalidHistoryQuery = errors.New("seq and at must not be negative")
+
+// BoardAtSeq reconstructs the board right after the event with sequence
+// number seq from the closest earlier snapshot and the events after it
+func (s *Service) BoardAtSeq(ctx context.Context, userID string, boardID string, seq int64) (*models.BoardHistoryDTO, error) {
+	if seq < 0 {
+		return nil, ErrInvalidHistoryQuery
+	}
+	if _, err := s.GetBoard(ctx, userID, boardID); err != nil {
+		return nil, err
+	}
+	return s.boardAtSeq(ctx, boardID, seq)
+}
+
+// BoardAtTime reconstructs the board as it was at ts (unix milliseconds)
+func (s *Service) BoardAtTime(ctx context.Context, userID string, boardID string, ts int64) (*models.BoardHistoryDTO, error) {
+	if ts < 0 {
+		return nil, ErrInvalidHistoryQuery
+	}
+	if _, err := s.GetBoard(ctx, userID, boardID); err != nil {
+		return nil, err
+	}
+
+	unsaved := s.unsavedEvents(boardID)
+	seq, err := s.DB.FindSeqAtTime(ctx, boardID, ts)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range unsaved {
+		if event.Timestamp <= ts && event.Seq > seq {
+			seq = event.Seq
+		}
+	}
+
+	return s.boardAtSeq(ctx, boardID, seq)
+}
+
+func (s *Service) boardAtSeq(ctx context.Context, boardID string, seq int64) (*models.BoardHistoryDTO, error) {
+	unsaved := s.unsavedEvents(boardID)
+	res := &models.BoardHistoryDTO{BoardID: boardID}
+	state := canvas.NewState()
+	afterID := ""
+
+	snapshot, err := s.DB.FindSnapshotAtSeq(ctx, boardID, seq)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		state = canvas.FromObjects(snapshot.Objects)
+		afterID = snapshot.LastEventID.Hex()
+		res.Seq = snapshot.LastSeq
+	}
+
+	stored, err := s.DB.FindEventsUpToSeq(ctx, boardID, afterID, seq)
+	if err != nil {
+		return nil, err
+	}
+
+	apply := func(event models.Event) {
+		state.Apply(event)
+		if event.Seq > res.Seq {
+			res.Seq = event.Seq
+			res.Timestamp = event.Timestamp
+		}
+	}
+
+	for _, e := range stored {
+		event, err := toEventModel(e)
+		if err != nil {
+			logger.Error("Skipping stored event: %s", err)
+			continue
+		}
+		apply(event)
+	}
+
+	for _, event := range unsaved {
+		if event.Seq > res.Seq && event.Seq <= seq {
+			apply(event)
+		}
+	}
+
+	res.Objects = state.Objects()
+	return res, nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/shared-drawboard/internal/database"
+	"github.com/shared-drawboard/internal/models"
+	"github.com/shared-drawboard/pkg/auth"
+)
+
+var (
+	ErrInviteNotFound      = errors.New("invite not found")
+	ErrInvalidInvite       = errors.New("invite is invalid, expired or revoked")
+	ErrInvalidInviteExpiry = errors.New("invite expiry must be between one minute and 30 days")
+)
+
+const (
+	DefaultInviteExpiry = 7 * 24 * time.Hour
+	MaxInviteExpiry     = 30 * 24 * time.Hour
+)
+
+func toInviteModel(i *database.Invite) models.Invite {
+	return models.Invite{
+		ID:        i.ID.Hex(),
+		BoardID:   i.BoardID,
+		Role:      models.BoardRole(i.Role),
+		CreatedBy: i.CreatedBy,
+		CreatedAt: i.CreatedAt,
+		ExpiresAt: i.ExpiresAt,
+		Uses:      i.Uses,
+	}
+}
+
+// CreateInvite stores an invite to the owner's board and returns it with its signed token
+func (s *Service) CreateInvite(ctx context.Context, userID string, boardID string, req models.InviteCreateDTO) (*models.Invite, error) {
+	if req.Role != models.RoleEditor && req.Role != models.RoleViewer {
+		return nil, ErrInvalidRole
+	}
+
+	expiry := DefaultInviteExpiry
+	if req.ExpiresIn != 0 {
+		expiry = time.Duration(req.ExpiresIn) * time.Second
+		if expiry < time.Minute || expiry > MaxInviteExpiry {
+			return nil, ErrInvalidInviteExpiry
+		}
+	}
+
+	if _, err := s.ownBoard(ctx, userID, boardID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(expiry)
+	invite := models.Invite{
+		BoardID:   boardID,
+		Role:      req.Role,
+		CreatedBy: userID,
+		CreatedAt: strconv.FormatInt(now.Unix(), 10),
+		ExpiresAt: strconv.FormatInt(expiresAt.Unix(), 10),
+	}
+
+	var err error
+	invite.ID, err = s.DB.CreateInvite(ctx, invite)
+	if err != nil {
+		return nil, err
+	}
+
+	invite.Token, err = auth.CreateInviteToken(invite.ID, boardID, string(req.Role), expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// ListInvites returns the invites of the owner's board without their tokens
+func (s *Service) ListInvites(ctx context.Context, userID string, boardID string) ([]models.Invite, error) {
+	if _, err := s.ownBoard(ctx, userID, boardID); err != nil {
+		return nil, err
+	}
+
+	invites, err := s.DB.FindInvitesByBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.Invite, 0, len(invites))
+	for i := range invites {
+		res = append(res, toInviteModel(&invites[i]))
+	}
+	return res, nil
+}
+
+// RevokeInvite deletes the invite, its token can no longer be redeemed
+func (s *Service) RevokeInvite(ctx context.Context, userID string, boardID string, inviteID string) error {
+	if _, err := s.ownBoard(ctx, userID, boardID); err != nil {
+		return err
+	}
+
+	deleted, err := s.DB.DeleteInvite(ctx, boardID, inviteID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrInviteNotFound
+	}
+	return nil
+}
+
+// RedeemInvite makes userID a member of the invite's board. Existing access
+// is never lowered, so an editor redeeming a viewer invite stays an editor.
+func (s *Service) RedeemInvite(ctx context.Context, userID string, token string) (*models.Board, error) {
+	claims, err := auth.VerifyInviteToken(token)
+	if err != nil {
+		return nil, ErrInvalidInvite
+	}
+
+	invite, err := s.DB.FindInvite(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if invite == nil || invite.BoardID != claims.Board {
+		return nil, ErrInvalidInvite
+	}
+	if expiresAt, err := strconv.ParseInt(invite.ExpiresAt, 10, 64); err != nil || time.Now().Unix() >= expiresAt {
+		return nil, ErrInvalidInvite
+	}
+
+	board, err := s.FindBoard(ctx, invite.BoardID)
+	if err != nil {
+		return nil, err
+	}
+	if board.Archived {
+		return nil, ErrInvalidInvite
+	}
+
+	current, err := s.GetBoard(ctx, userID, board.ID)
+	switch {
+	case err == nil && current.Role.CanEdit():
+		return current, nil
+	case err != nil && !errors.Is(err, ErrBoardForbidden):
+		return nil, err
+	}
+
+	err = s.DB.SaveBoardMember(ctx, models.BoardMember{
+		BoardID:   board.ID,
+		UserID:    userID,
+		Role:      models.BoardRole(invite.Role),
+		CreatedAt: strconv.FormatInt(time.Now().Unix(), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.DB.IncrementInviteUses(ctx, invite.ID.Hex()); err != nil {
+		return nil, err
+	}
+
+	board.Role = models.BoardRole(invite.Role)
+	return board, nil
+}
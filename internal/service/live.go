@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/shared-drawboard/internal/canvas"
+	"github.com/shared-drawboard/internal/models"
+)
+
+// BoardIdleTimeout is how long a live board stays in memory without activity
+const BoardIdleTimeout = 30 * time.Minute
+
+// MaxCatchUpEvents caps how many events are replayed to a client catching up,
+// beyond that sending the whole board is cheaper
+const MaxCatchUpEvents = 5000
+
+// liveBoard returns the in-memory board, loading it from storage plus the
+// events still waiting in the batch buffer the first time it is used
+func (s *Service) liveBoard(ctx context.Context, boardID string) (*canvas.Board, error) {
+	s.liveMu.Lock()
+	board, ok := s.live[boardID]
+	s.liveMu.Unlock()
+	if ok {
+		return board, nil
+	}
+
+	unsaved := s.unsavedEvents(boardID)
+	stored, err := s.loadStoredState(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	seq := stored.lastSeq
+	for _, event := range unsaved {
+		if event.Seq > seq {
+			stored.state.Apply(event)
+			seq = event.Seq
+		}
+	}
+
+	s.liveMu.Lock()
+	defer s.liveMu.Unlock()
+
+	// another connection may have loaded the board meanwhile
+	if board, ok := s.live[boardID]; ok {
+		return board, nil
+	}
+	if s.live == nil {
+		s.live = make(map[string]*canvas.Board)
+	}
+	board = canvas.NewBoard(boardID, stored.state, seq)
+	s.live[boardID] = board
+	return board, nil
+}
+
+func (s *Service) evictIdleBoards(idle time.Duration) {
+	s.liveMu.Lock()
+	defer s.liveMu.Unlock()
+
+	for boardID, board := range s.live {
+		if board.IdleSince(idle) {
+			delete(s.live, boardID)
+		}
+	}
+}
+
+func (s *Service) dropLiveBoard(boardID string) {
+	s.liveMu.Lock()
+	defer s.liveMu.Unlock()
+
+	delete(s.live, boardID)
+}
+
+// BoardState returns the authoritative objects of a board and the sequence number they reflect
+func (s *Service) BoardState(ctx context.Context, boardID string) ([]models.BoardObject, int64, error) {
+	board, err := s.liveBoard(ctx, boardID)
+	if err != nil {
+		return nil, 0, err
+	}
+	objects, seq := board.Snapshot()
+	return objects, seq, nil
+}
+
+// CommitEvent sequences the event on the live board. publish is called in
+// sequence order once the board is unlocked, before CommitEvent returns.
+func (s *Service) CommitEvent(ctx context.Context, boardID string, event models.Event, publish func(models.Event)) (models.Event, error) {
+	board, err := s.liveBoard(ctx, boardID)
+	if err != nil {
+		return models.Event{}, err
+	}
+	return board.Commit(event, publish), nil
+}
+
+// Undo reverts the latest operation of the user on the board, reporting false when there was none
+func (s *Service) Undo(ctx context.Context, boardID string, userID string, clientID string, publish func(models.Event)) (bool, error) {
+	board, err := s.liveBoard(ctx, boardID)
+	if err != nil {
+		return false, err
+	}
+	return board.Undo(userID, clientID, publish), nil
+}
+
+// Redo reapplies the latest operation the user undid on the board, reporting false when there was none
+func (s *Service) Redo(ctx context.Context, boardID string, userID string, clientID string, publish func(models.Event)) (bool, error) {
+	board, err := s.liveBoard(ctx, boardID)
+	if err != nil {
+		return false, err
+	}
+	return board.Redo(userID, clientID, publish), nil
+}
+
+// EventsSince returns the events of a board after seq, from memory or else
+// from the events collection and the batch buffer. ok is false when they
+// can't be replayed and the caller has to resend the whole board.
+func (s *Service) EventsSince(ctx context.Context, boardID string, seq int64) ([]models.Event, bool, error) {
+	board, err := s.liveBoard(ctx, boardID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if seq > board.Seq() {
+		return nil, false, nil
+	}
+
+	recent, complete := board.EventsSince(seq)
+	if complete {
+		return recent, true, nil
+	}
+
+	before := board.Seq() + 1
+	if len(recent) > 0 {
+		before = recent[0].Seq
+	}
+	if before-seq > MaxCatchUpEvents {
+		return nil, false, nil
+	}
+
+	older, err := s.storedEventsBySeq(ctx, boardID, seq, before)
+	if err != nil {
+		return nil, false, err
+	}
+
+	events := append(older, recent...)
+	for i, event := range events {
+		if event.Seq != seq+int64(i)+1 {
+			// history has holes, e.g. events stored before sequencing existed
+			return nil, false, nil
+		}
+	}
+	return events, true, nil
+}
+
+// storedEventsBySeq returns the events with afterSeq < seq < beforeSeq that are
+// either stored or still waiting in the batch buffer
+func (s *Service) storedEventsBySeq(ctx context.Context, boardID string, afterSeq int64, beforeSeq int64) ([]models.Event, error) {
+	unsaved := s.unsavedEvents(boardID)
+	stored, err := s.DB.FindEventsBySeq(ctx, boardID, afterSeq, beforeSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.Event, 0, len(stored))
+	lastSeq := afterSeq
+	for _, e := range stored {
+		event, err := toEventModel(e)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+		lastSeq = max(lastSeq, event.Seq)
+	}
+
+	for _, event := range unsaved {
+		if event.Seq > lastSeq && event.Seq < beforeSeq {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events, nil
+}
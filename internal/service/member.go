@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/shared-drawboard/internal/database"
+	"github.com/shared-drawboard/internal/models"
+)
+
+var (
+	ErrInvalidRole  = errors.New("role must be editor or viewer")
+	ErrUserNotFound = errors.New("user not found")
+	ErrOwnerMember  = errors.New("the owner of a board cannot be a member")
+)
+
+func toMemberModel(m *database.BoardMember) models.BoardMember {
+	return models.BoardMember{
+		BoardID:   m.BoardID,
+		UserID:    m.UserID,
+		Role:      models.BoardRole(m.Role),
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// ListMembers returns the owner followed by the members of the board,
+// every member may see who else has access
+func (s *Service) ListMembers(ctx context.Context, userID string, boardID string) ([]models.BoardMember, error) {
+	board, err := s.GetBoard(ctx, userID, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.DB.FindBoardMembers(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.BoardMember, 0, len(members)+1)
+	res = append(res, models.BoardMember{
+		BoardID:   boardID,
+		UserID:    board.OwnerID,
+		Role:      models.RoleOwner,
+		CreatedAt: board.CreatedAt,
+	})
+	for i := range members {
+		res = append(res, toMemberModel(&members[i]))
+	}
+	return res, nil
+}
+
+// SetMember gives memberID the role on the board, only the owner may share it
+func (s *Service) SetMember(ctx context.Context, userID string, boardID string, memberID string, role models.BoardRole) (*models.BoardMember, error) {
+	if role != models.RoleEditor && role != models.RoleViewer {
+		return nil, ErrInvalidRole
+	}
+
+	board, err := s.ownBoard(ctx, userID, boardID)
+	if err != nil {
+		return nil, err
+	}
+	if memberID == board.OwnerID {
+		return nil, ErrOwnerMember
+	}
+
+	user, err := s.DB.FindBy(ctx, "Email", memberID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	member := models.BoardMember{
+		BoardID:   boardID,
+		UserID:    memberID,
+		Role:      role,
+		CreatedAt: strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	if err := s.DB.SaveBoardMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// RemoveMember revokes the access of memberID. The owner may remove anyone,
+// members may only remove themselves.
+func (s *Service) RemoveMember(ctx context.Context, userID string, boardID string, memberID string) error {
+	board, err := s.GetBoard(ctx, userID, boardID)
+	if err != nil {
+		return err
+	}
+	if board.Role != models.RoleOwner && userID != memberID {
+		return ErrBoardForbidden
+	}
+	if memberID == board.OwnerID {
+		return ErrOwnerMember
+	}
+	return s.DB.DeleteBoardMember(ctx, boardID, memberID)
+}
@@ -4,16 +4,33 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/shared-drawboard/internal/canvas"
 	"github.com/shared-drawboard/internal/database"
 	"github.com/shared-drawboard/internal/models"
 	"github.com/shared-drawboard/pkg/auth"
+	"github.com/shared-drawboard/pkg/logger"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
 	DB database.DB
+
+	// events received by BatchSaver that are not stored yet
+	pending []models.Event
+	// the batch flushPending is writing, replayed like pending until it is stored
+	inflight  []models.Event
+	pendingMu sync.Mutex
+	// held for a whole flush, so a deleted board's events are not stored after it
+	flushMu sync.Mutex
+	// boards with stored events that no snapshot covers yet, guarded by pendingMu
+	dirty map[string]struct{}
+
+	// authoritative boards currently kept in memory
+	live   map[string]*canvas.Board
+	liveMu sync.Mutex
 }
 
 func New() (s *Service, err error) {
@@ -59,23 +76,61 @@ func (s *Service) GetUser(ctx context.Context, value string) (*models.User, erro
 	return &u, nil
 }
 
-func (s *Service) CreateSession(ctx context.Context, userID string) (*models.SessionDTO, error) {
-	refreshtokenString, err := auth.CreateRefreshToken(32)
+// SSOUser returns the user with the email the identity provider signed in,
+// creating one on the first single sign-on. A created user gets a random
+// password nobody knows, so they can only sign in through the provider.
+func (s *Service) SSOUser(ctx context.Context, email string, name string) (*models.User, error) {
+	existing, err := s.DB.FindBy(ctx, "Email", email)
 	if err != nil {
-		return &models.SessionDTO{}, err
+		return nil, err
+	}
+	if existing != nil {
+		return &models.User{ID: existing.ID.Hex(), Name: existing.Name, Email: existing.Email}, nil
+	}
+
+	password, err := auth.CreateRefreshToken(32)
+	if err != nil {
+		return nil, err
+	}
+	u := models.User{Name: name, Email: email, Password: password}
+	id, err := s.SaveUser(ctx, u)
+	if err == ErrUserExists {
+		// a second login of the same user created it first
+		return s.SSOUser(ctx, email, name)
+	}
+	if err != nil {
+		return nil, err
 	}
+	return &models.User{ID: id, Name: name, Email: email}, nil
+}
+
+// DisplayName returns the name other users see, empty when the user has
+// none. It never falls back to the email, public viewers see the name too.
+func (s *Service) DisplayName(ctx context.Context, userID string) string {
+	user, err := s.DB.FindBy(ctx, "Email", userID)
+	if err != nil || user == nil {
+		return ""
+	}
+	return user.Name
+}
 
-	refreshtokenHash, err := bcrypt.GenerateFromPassword([]byte(refreshtokenString), bcrypt.DefaultCost)
+// CreateSession signs in a new device, the user's other sessions stay valid
+func (s *Service) CreateSession(ctx context.Context, userID string, userAgent string, ip string) (*models.SessionDTO, error) {
+	refreshtokenString, err := auth.CreateRefreshToken(32)
 	if err != nil {
 		return &models.SessionDTO{}, err
 	}
 
 	sDTO := models.SessionDTO{
 		UserID:     userID,
-		TokenHash:  string(refreshtokenHash),
-		ExpiresAt:  strconv.FormatInt(time.Now().Add(24*7*time.Hour).Unix(), 10),
+		TokenHash:  auth.HashRefreshToken(refreshtokenString),
+		UserAgent:  userAgent,
+		IP:         ip,
+		ExpiresAt:  time.Now().Add(24 * 7 * time.Hour),
 		CreatedAt:  strconv.FormatInt(time.Now().Unix(), 10),
 		LastUsedAt: strconv.FormatInt(time.Now().Unix(), 10),
+		// the cookie gets the token itself, only its hash is stored
+		RefreshToken: refreshtokenString,
 	}
 
 	sDTO.ID, err = s.DB.CreateSession(ctx, sDTO)
@@ -86,89 +141,178 @@ func (s *Service) CreateSession(ctx context.Context, userID string) (*models.Ses
 	return &sDTO, nil
 }
 
-func (s *Service) UpdateSession(ctx context.Context, tokenDTO models.RefreshTokenDTO) (*models.RefreshTokenDTO, error) {
+// UpdateSession checks the refresh token of the session tokenDTO.SessionID
+// and rotates it, every refresh token can be used once. See verifyRefreshToken.
+func (s *Service) UpdateSession(ctx context.Context, tokenDTO models.RefreshTokenDTO, userAgent string, ip string) (*models.RefreshTokenDTO, error) {
 
 	uid := tokenDTO.UserID
+	sessionID := tokenDTO.SessionID
 
-	//create new refresh token, auth token
-	newAuthToken, err := auth.CreateJWTToken(uid, time.Now().Add(15*time.Minute).Unix())
+	presentedHash, err := s.verifyRefreshToken(ctx, uid, sessionID, tokenDTO.RefreshToken)
 	if err != nil {
 		return &models.RefreshTokenDTO{}, err
 	}
 
+	//create new refresh token, auth token
 	newRefreshToken, err := auth.CreateRefreshToken(32)
 	if err != nil {
 		return &models.RefreshTokenDTO{}, err
 	}
 
-	newRefreshtokenHash, err := bcrypt.GenerateFromPassword([]byte(newRefreshToken), bcrypt.DefaultCost)
+	// losing the race against another refresh with the same token is not a theft,
+	// the loser has to sign in again or use the token the winner got
+	rotated, err := s.DB.RotateSession(ctx, models.SessionDTO{
+		ID:        sessionID,
+		UserID:    uid,
+		TokenHash: auth.HashRefreshToken(newRefreshToken),
+		UserAgent: userAgent,
+		IP:        ip,
+	}, presentedHash)
+	if err != nil {
+		return &models.RefreshTokenDTO{}, err
+	}
+	if !rotated {
+		return &models.RefreshTokenDTO{}, ErrInvalidRefreshToken
+	}
+
+	newAuthToken, err := auth.CreateJWTToken(uid, sessionID, time.Now().Add(15*time.Minute).Unix())
 	if err != nil {
 		return &models.RefreshTokenDTO{}, err
 	}
 
 	newTokenDTO := models.RefreshTokenDTO{
 		UserID:           uid,
+		SessionID:        sessionID,
 		AuthToken:        newAuthToken,
 		AuthExpiresAt:    strconv.FormatInt(time.Now().Add(15*time.Minute).Unix(), 10), // 15 minutes
 		RefreshToken:     newRefreshToken,
 		RefreshExpriesAt: strconv.FormatInt(time.Now().Add(24*7*time.Hour).Unix(), 10), // 7 days
 	}
 
-	_, err = s.DB.UpdateSession(ctx, uid, string(newRefreshtokenHash))
-	if err != nil {
-		return &models.RefreshTokenDTO{}, err
-	}
-
 	return &newTokenDTO, nil
 }
 
+const (
+	// MaxPendingEvents caps the events kept while the DB refuses writes, the
+	// oldest are dropped beyond it
+	MaxPendingEvents = 100000
+	// maxFlushBackoff is the longest wait between retries of a failing batch save
+	maxFlushBackoff = 2 * time.Minute
+)
+
+// BatchSaver buffers incoming events and writes them to the DB in batches.
+// A failed write is logged and retried on a later tick, waiting twice as
+// long after every failure in a row.
 func (s *Service) BatchSaver(events <-chan models.Event, BatchSize int64) error {
-	batch := make([]interface{}, 0)
-	timer := time.NewTicker(10 * time.Second)
+	const interval = 10 * time.Second
+	timer := time.NewTicker(interval)
 	defer timer.Stop()
 
+	var failures int
+	var backoff time.Duration
+	var retryAt time.Time
+	flush := func() {
+		if time.Now().Before(retryAt) {
+			return
+		}
+		if err := s.flushPending(); err != nil {
+			failures++
+			backoff = min(max(2*backoff, interval), maxFlushBackoff)
+			retryAt = time.Now().Add(backoff)
+			logger.Error("Batch save failed %d times in a row: %s", failures, err)
+			return
+		}
+		failures = 0
+		backoff = 0
+		retryAt = time.Time{}
+	}
+
 	for {
 		select {
 		case event, ok := <-events:
 			if !ok {
-				if len(batch) > 0 {
-					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-					defer cancel()
-					if err := s.DB.BatchSave(ctx, batch); err != nil {
-						return err
-					}
-				}
-				return nil
+				return s.flushPending()
 			}
 
-			if len(batch) < int(BatchSize) {
-				batch = append(batch, event)
-			} else {
-				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-				defer cancel()
-
-				err := s.DB.BatchSave(ctx, batch)
-				if err != nil {
-					return err
-				}
+			s.pendingMu.Lock()
+			s.pending = append(s.pending, event)
+			if dropped := len(s.pending) - MaxPendingEvents; dropped > 0 {
+				s.pending = append([]models.Event(nil), s.pending[dropped:]...)
+				logger.Error("Batch save is behind, dropped %d unsaved events", dropped)
+			}
+			full := len(s.pending) >= int(BatchSize)
+			s.pendingMu.Unlock()
 
-				batch = make([]interface{}, 0)
+			if full {
+				flush()
 			}
 
 		case <-timer.C:
-			if len(batch) == 0 {
-				continue
-			}
+			flush()
+		}
+	}
+}
 
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
+// flushPending writes the pending events without holding pendingMu, so new
+// events keep coming in during a slow write. Until the write is done the
+// batch is inflight, where replays still find it. A failed batch goes back
+// in front of the pending events.
+func (s *Service) flushPending() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.pendingMu.Lock()
+	events := s.pending
+	s.pending = nil
+	s.inflight = events
+	s.pendingMu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
 
-			err := s.DB.BatchSave(ctx, batch)
-			if err != nil {
-				return err
-			}
+	batch := make([]interface{}, 0, len(events))
+	for _, event := range events {
+		batch = append(batch, event)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.DB.BatchSave(ctx, batch)
 
-			batch = make([]interface{}, 0)
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	s.inflight = nil
+	if err != nil {
+		s.pending = append(events, s.pending...)
+		return err
+	}
+
+	if s.dirty == nil {
+		s.dirty = make(map[string]struct{})
+	}
+	for _, event := range events {
+		s.dirty[event.BoardID] = struct{}{}
+	}
+	return nil
+}
+
+// unsavedEvents returns the events of the board that are not stored yet, in
+// the order they were committed. Readers take them before querying the DB:
+// an event stored meanwhile then shows up twice, and only the copies with a
+// seq above the stored ones are kept.
+func (s *Service) unsavedEvents(boardID string) []models.Event {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	var events []models.Event
+	for _, list := range [][]models.Event{s.inflight, s.pending} {
+		for _, event := range list {
+			if event.BoardID == boardID {
+				events = append(events, event)
+			}
 		}
 	}
+	return events
 }
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shared-drawboard/internal/models"
+)
+
+func TestFlushPending(t *testing.T) {
+	const boardID = "board"
+	events := []models.Event{
+		{BoardID: boardID, Seq: 1, Type: models.BoardClear},
+		{BoardID: boardID, Seq: 2, Type: models.BoardClear},
+	}
+
+	t.Run("batch is replayed while it is written", func(t *testing.T) {
+		db := newMemoryDB()
+		s := &Service{DB: db, pending: append([]models.Event(nil), events...)}
+
+		// would deadlock if the write held pendingMu
+		db.beforeSave = func() {
+			s.pendingMu.Lock()
+			s.pending = append(s.pending, models.Event{BoardID: boardID, Seq: 3, Type: models.BoardClear})
+			s.pendingMu.Unlock()
+
+			if got := len(s.unsavedEvents(boardID)); got != 3 {
+				t.Errorf("unsaved events during the write = %d, want 3", got)
+			}
+		}
+
+		if err := s.flushPending(); err != nil {
+			t.Fatal(err)
+		}
+		if len(db.events) != 2 {
+			t.Errorf("stored %d events, want 2", len(db.events))
+		}
+		if unsaved := s.unsavedEvents(boardID); len(unsaved) != 1 || unsaved[0].Seq != 3 {
+			t.Errorf("unsaved events after the write = %v, want only seq 3", unsaved)
+		}
+		if _, ok := s.dirty[boardID]; !ok {
+			t.Error("board is not marked for compaction")
+		}
+	})
+
+	t.Run("failed batch goes back in front", func(t *testing.T) {
+		db := newMemoryDB()
+		db.saveErr = errors.New("write failed")
+		s := &Service{DB: db, pending: append([]models.Event(nil), events...)}
+		db.beforeSave = func() {
+			s.pendingMu.Lock()
+			s.pending = append(s.pending, models.Event{BoardID: boardID, Seq: 3, Type: models.BoardClear})
+			s.pendingMu.Unlock()
+		}
+
+		if err := s.flushPending(); err == nil {
+			t.Fatal("flushPending did not report the failed write")
+		}
+		unsaved := s.unsavedEvents(boardID)
+		if len(unsaved) != 3 {
+			t.Fatalf("unsaved events = %d, want 3", len(unsaved))
+		}
+		for i, event := range unsaved {
+			if event.Seq != int64(i+1) {
+				t.Errorf("unsaved event %d has seq %d, want %d", i, event.Seq, i+1)
+			}
+		}
+	})
+}
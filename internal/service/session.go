@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/shared-drawboard/internal/models"
+	"github.com/shared-drawboard/pkg/auth"
+)
+
+var (
+	ErrSessionNotFound     = errors.New("session not found or ended")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used, the session has been ended")
+)
+
+// RefreshReuseGrace is how long after a rotation the replaced token is only
+// rejected, so two tabs refreshing at once do not end their own session
+const RefreshReuseGrace = 10 * time.Second
+
+// verifyRefreshToken checks token against the current refresh token of the
+// session and returns its hash. A token the session already rotated out was
+// copied from its owner, the whole session is ended then.
+func (s *Service) verifyRefreshToken(ctx context.Context, userID string, sessionID string, token string) (string, error) {
+	session, err := s.DB.FindSession(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	if session == nil || session.UserID != userID {
+		return "", ErrSessionNotFound
+	}
+
+	now := time.Now()
+	// the TTL index removes expired sessions, but only about once a minute
+	if !now.Before(session.ExpiresAt) {
+		if _, err := s.DB.DeleteSession(ctx, sessionID); err != nil {
+			return "", err
+		}
+		return "", ErrSessionNotFound
+	}
+
+	hash := auth.HashRefreshToken(token)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(session.TokenHash)) == 1 {
+		return hash, nil
+	}
+
+	for i, rotated := range session.RotatedHashes {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(rotated)) != 1 {
+			continue
+		}
+
+		lastUsed, _ := strconv.ParseInt(session.LastUsedAt, 10, 64)
+		if i == len(session.RotatedHashes)-1 && now.Sub(time.Unix(lastUsed, 0)) < RefreshReuseGrace {
+			return "", ErrInvalidRefreshToken
+		}
+
+		if _, err := s.DB.DeleteSession(ctx, sessionID); err != nil {
+			return "", err
+		}
+		return "", ErrRefreshTokenReused
+	}
+	return "", ErrInvalidRefreshToken
+}
+
+// Logout ends the session an auth token was issued for. Tokens without a
+// session predate session tracking, every session of the user is ended then.
+func (s *Service) Logout(ctx context.Context, userID string, sessionID string) error {
+	if sessionID == "" {
+		return s.LogoutEverywhere(ctx, userID)
+	}
+
+	session, err := s.DB.FindSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		// already ended, by another logout or by its refresh token expiring
+		return nil
+	}
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	_, err = s.DB.DeleteSession(ctx, sessionID)
+	return err
+}
+
+// ListSessions returns the devices the user is signed in on, most recently
+// used first. currentID is the session of the asking auth token.
+func (s *Service) ListSessions(ctx context.Context, userID string, currentID string) ([]models.SessionInfo, error) {
+	sessions, err := s.DB.FindSessionsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	res := make([]models.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		// expired sessions can no longer be refreshed
+		if !session.ExpiresAt.After(now) {
+			continue
+		}
+		id := session.ID.Hex()
+		res = append(res, models.SessionInfo{
+			ID:         id,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  strconv.FormatInt(session.ExpiresAt.Unix(), 10),
+			Current:    id == currentID,
+		})
+	}
+	return res, nil
+}
+
+// RevokeSession signs one of the user's devices out
+func (s *Service) RevokeSession(ctx context.Context, userID string, sessionID string) error {
+	session, err := s.DB.FindSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	_, err = s.DB.DeleteSession(ctx, sessionID)
+	return err
+}
+
+// LogoutEverywhere ends every session of the user
+func (s *Service) LogoutEverywhere(ctx context.Context, userID string) error {
+	return s.DB.DeleteSessions(ctx, userID)
+}
+
+// SessionActive reports whether the session an auth token was issued for
+// has not been ended or expired, tokens without a session are always accepted
+func (s *Service) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	if sessionID == "" {
+		return true, nil
+	}
+
+	session, err := s.DB.FindSession(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	// the TTL index removes expired sessions, but only about once a minute
+	return session != nil && time.Now().Before(session.ExpiresAt), nil
+}
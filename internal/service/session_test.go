@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shared-drawboard/internal/database"
+	"github.com/shared-drawboard/internal/models"
+	"github.com/shared-drawboard/pkg/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const sessionUser = "user@example.com"
+
+// storeSession adds a session whose refresh token is "current" and which
+// replaced the tokens rotated before, oldest first. It was last used lastUsed ago.
+func storeSession(db *memoryDB, lastUsed time.Duration, expiresIn time.Duration, rotated ...string) string {
+	id := primitive.NewObjectID()
+	hashes := make([]string, 0, len(rotated))
+	for _, token := range rotated {
+		hashes = append(hashes, auth.HashRefreshToken(token))
+	}
+	db.sessions[id.Hex()] = database.Session{
+		ID:            id,
+		UserID:        sessionUser,
+		TokenHash:     auth.HashRefreshToken("current"),
+		ExpiresAt:     time.Now().Add(expiresIn),
+		CreatedAt:     strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10),
+		LastUsedAt:    strconv.FormatInt(time.Now().Add(-lastUsed).Unix(), 10),
+		RotatedHashes: hashes,
+	}
+	return id.Hex()
+}
+
+func TestVerifyRefreshToken(t *testing.T) {
+	week := 24 * 7 * time.Hour
+	tests := []struct {
+		name      string
+		userID    string
+		token     string
+		lastUsed  time.Duration
+		expiresIn time.Duration
+		wantErr   error
+		wantEnded bool
+	}{
+		{"current token", sessionUser, "current", time.Minute, week, nil, false},
+		{"unknown token", sessionUser, "guessed", time.Minute, week, ErrInvalidRefreshToken, false},
+		{"replaced token inside the grace period", sessionUser, "previous", time.Second, week, ErrInvalidRefreshToken, false},
+		{"replaced token after the grace period", sessionUser, "previous", RefreshReuseGrace + 2*time.Second, week, ErrRefreshTokenReused, true},
+		{"older token inside the grace period", sessionUser, "older", time.Second, week, ErrRefreshTokenReused, true},
+		{"session of another user", "other@example.com", "current", time.Minute, week, ErrSessionNotFound, false},
+		{"expired session", sessionUser, "current", time.Minute, -time.Second, ErrSessionNotFound, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			s := &Service{DB: db}
+			sessionID := storeSession(db, tt.lastUsed, tt.expiresIn, "older", "previous")
+
+			hash, err := s.verifyRefreshToken(context.Background(), tt.userID, sessionID, tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("verifyRefreshToken error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && hash != auth.HashRefreshToken(tt.token) {
+				t.Errorf("verifyRefreshToken returned hash %q, want the hash of the token", hash)
+			}
+			if _, ok := db.sessions[sessionID]; ok == tt.wantEnded {
+				t.Errorf("session kept = %v, want %v", ok, !tt.wantEnded)
+			}
+		})
+	}
+}
+
+func TestUpdateSessionLostRace(t *testing.T) {
+	db := newMemoryDB()
+	s := &Service{DB: db}
+	sessionID := storeSession(db, time.Minute, time.Hour)
+
+	// another tab refreshes with the same token between the check and the rotation
+	winner := auth.HashRefreshToken("winner")
+	db.beforeRotate = func() {
+		rotated, err := db.RotateSession(context.Background(), models.SessionDTO{
+			ID:        sessionID,
+			UserID:    sessionUser,
+			TokenHash: winner,
+		}, auth.HashRefreshToken("current"))
+		if err != nil || !rotated {
+			t.Fatalf("winning rotation failed: %v %v", rotated, err)
+		}
+	}
+
+	_, err := s.UpdateSession(context.Background(), models.RefreshTokenDTO{
+		UserID:       sessionUser,
+		SessionID:    sessionID,
+		RefreshToken: "current",
+	}, "test", "127.0.0.1")
+	if !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("UpdateSession error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+
+	session, ok := db.sessions[sessionID]
+	if !ok {
+		t.Fatal("losing the race ended the session")
+	}
+	if session.TokenHash != winner {
+		t.Errorf("session holds %q, want the winner's token", session.TokenHash)
+	}
+
+	// the loser retrying with its token right away is refused, not taken for a theft
+	if _, err := s.verifyRefreshToken(context.Background(), sessionUser, sessionID, "current"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("retry error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+	if _, ok := db.sessions[sessionID]; !ok {
+		t.Error("retrying after the race ended the session")
+	}
+}
+
+func TestSessionActive(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresIn time.Duration
+		ended     bool
+		want      bool
+	}{
+		{"active session", time.Hour, false, true},
+		{"expired session the TTL index did not remove yet", -time.Second, false, false},
+		{"ended session", time.Hour, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMemoryDB()
+			s := &Service{DB: db}
+			sessionID := storeSession(db, time.Minute, tt.expiresIn)
+			if tt.ended {
+				delete(db.sessions, sessionID)
+			}
+
+			active, err := s.SessionActive(context.Background(), sessionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if active != tt.want {
+				t.Errorf("SessionActive = %v, want %v", active, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shared-drawboard/internal/canvas"
+	"github.com/shared-drawboard/internal/database"
+	"github.com/shared-drawboard/internal/models"
+	"github.com/shared-drawboard/pkg/logger"
+)
+
+// toEventModel decodes the stored data payload into the struct matching the event type
+func toEventModel(e database.Event) (models.Event, error) {
+	event := models.Event{
+		BoardID:   e.BoardID,
+		Seq:       e.Seq,
+		UserID:    e.UserID,
+		ClientID:  e.ClientID,
+		Action:    models.EventAction(e.Action),
+		Type:      models.EventType(e.Type),
+		Tool:      e.Tool,
+		CreatedAt: e.CreatedAt,
+		Timestamp: e.Timestamp,
+	}
+
+	var err error
+	switch event.Type {
+	case models.FreehandDraw:
+		var data models.FreehandDrawData
+		err = e.Data.Unmarshal(&data)
+		event.Data = data
+
+	case models.ShapeCreate:
+		var data models.ShapeCreateData
+		err = e.Data.Unmarshal(&data)
+		event.Data = data
+
+	case models.TextAdd:
+		var data models.TextAddData
+		err = e.Data.Unmarshal(&data)
+		event.Data = data
+
+	case models.ObjectDelete:
+		var data models.ObjectDeleteData
+		err = e.Data.Unmarshal(&data)
+		event.Data = data
+
+	case models.ObjectUpdate:
+		var data models.ObjectUpdateData
+		err = e.Data.Unmarshal(&data)
+		event.Data = data
+
+	case models.BoardClear:
+		event.Data = nil
+
+	default:
+		err = fmt.Errorf("unknown event type %q", e.Type)
+	}
+
+	if err != nil {
+		return models.Event{}, fmt.Errorf("event %s: %w", e.ID.Hex(), err)
+	}
+	return event, nil
+}
+
+// storedState is a board rebuilt from its latest snapshot and the stored events after it
+type storedState struct {
+	state       *canvas.State
+	lastEventID string
+	lastSeq     int64
+	// number of stored events applied on top of the snapshot
+	count int
+}
+
+func (s *Service) loadStoredState(ctx context.Context, boardID string) (*storedState, error) {
+	res := &storedState{state: canvas.NewState()}
+
+	snapshot, err := s.DB.FindLatestSnapshot(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		res.state = canvas.FromObjects(snapshot.Objects)
+		res.lastEventID = snapshot.LastEventID.Hex()
+		res.lastSeq = snapshot.LastSeq
+	}
+
+	stored, err := s.DB.FindEvents(ctx, boardID, res.lastEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range stored {
+		res.lastEventID = e.ID.Hex()
+		if e.Seq > res.lastSeq {
+			res.lastSeq = e.Seq
+		}
+		event, err := toEventModel(e)
+		if err != nil {
+			logger.Error("Skipping stored event: %s", err)
+			continue
+		}
+		res.state.Apply(event)
+	}
+	res.count = len(stored)
+
+	return res, nil
+}
+
+// CompactBoard stores a new snapshot of the board once at least minEvents
+// stored events are not covered by the previous one. It returns how many
+// stored events are still outside a snapshot.
+func (s *Service) CompactBoard(ctx context.Context, boardID string, minEvents int) (int, error) {
+	stored, err := s.loadStoredState(ctx, boardID)
+	if err != nil {
+		return 0, err
+	}
+	if stored.count == 0 || stored.count < minEvents {
+		return stored.count, nil
+	}
+
+	_, err = s.DB.SaveSnapshot(ctx, models.Snapshot{
+		BoardID:     boardID,
+		Objects:     stored.state.Objects(),
+		LastEventID: stored.lastEventID,
+		LastSeq:     stored.lastSeq,
+		CreatedAt:   strconv.FormatInt(time.Now().Unix(), 10),
+	})
+	if err != nil {
+		return stored.count, err
+	}
+
+	logger.Info("Snapshot of board %s compacted %d events", boardID, stored.count)
+	return 0, nil
+}
+
+// Snapshotter periodically compacts the boards that had events stored since
+// the previous run. Boards below minEvents are checked again on the next run.
+// Live boards nobody touched for BoardIdleTimeout are dropped from memory.
+func (s *Service) Snapshotter(interval time.Duration, minEvents int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.pendingMu.Lock()
+		dirty := s.dirty
+		s.dirty = nil
+		s.pendingMu.Unlock()
+
+		for boardID := range dirty {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			remaining, err := s.CompactBoard(ctx, boardID, minEvents)
+			cancel()
+
+			if err != nil {
+				logger.Error("Snapshot of board %s failed: %s", boardID, err)
+			}
+			if err != nil || remaining > 0 {
+				s.markDirty(boardID)
+			}
+		}
+
+		s.evictIdleBoards(BoardIdleTimeout)
+	}
+}
+
+// dropPendingEvents forgets the events of a deleted board that are not stored
+// yet. It waits for a running flush, which may still be storing some of them.
+func (s *Service) dropPendingEvents(boardID string) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	kept := s.pending[:0]
+	for _, event := range s.pending {
+		if event.BoardID != boardID {
+			kept = append(kept, event)
+		}
+	}
+	s.pending = kept
+	delete(s.dirty, boardID)
+}
+
+func (s *Service) markDirty(boardID string) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if s.dirty == nil {
+		s.dirty = make(map[string]struct{})
+	}
+	s.dirty[boardID] = struct{}{}
+}
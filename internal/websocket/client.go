@@ -1,11 +1,66 @@
 package websocket
 
-import "github.com/gorilla/websocket"
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shared-drawboard/internal/models"
+)
 
 type Client struct {
 	ID     string
 	UserID string
-	Conn   *websocket.Conn
-	Send   chan []byte
-	//Board string
+	// SessionID is the login session of the auth token the client joined with
+	SessionID string
+	// Name is shown to the other clients of the board
+	Name  string
+	Board string
+	// Role is resolved when the client joins, a change of role disconnects it
+	Role models.BoardRole
+	Conn *websocket.Conn
+	Send chan []byte
+
+	// Strokes are the freehand strokes the client is drawing, by id,
+	// only used by its read loop
+	Strokes map[string]*Stroke
+
+	// token bucket for ephemeral messages, only used by the client's read loop
+	tokens     float64
+	lastRefill time.Time
+}
+
+// Stroke collects the points of a freehand stroke until it ends
+type Stroke struct {
+	Tool string
+	Data models.FreehandDrawData
+	// Relayed is how many of the points the rest of the board has been sent
+	Relayed int
+}
+
+const (
+	// EphemeralRate is how many ephemeral messages a client may send per second
+	EphemeralRate = 50
+	// EphemeralBurst is how many it may send at once after being idle
+	EphemeralBurst = 20
+)
+
+// AllowEphemeral reports whether the client may send another ephemeral
+// message, the ones over its rate are dropped
+func (c *Client) AllowEphemeral(now time.Time) bool {
+	if c.lastRefill.IsZero() {
+		c.tokens = EphemeralBurst
+	} else {
+		c.tokens = min(EphemeralBurst, c.tokens+now.Sub(c.lastRefill).Seconds()*EphemeralRate)
+	}
+	c.lastRefill = now
+
+	if c.tokens < 1 {
+		return false
+	}
+	c.tokens--
+	return true
+}
+
+func (c *Client) Presence() models.PresenceData {
+	return models.PresenceData{ClientID: c.ID, Name: c.Name, Anonymous: c.UserID == "", Role: c.Role}
 }
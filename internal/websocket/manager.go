@@ -1,55 +1,310 @@
 package websocket
 
 import (
+	"encoding/json"
 	"sync"
+	"time"
+
+	"github.com/shared-drawboard/internal/models"
+	"github.com/shared-drawboard/pkg/logger"
 )
 
+// Message is a payload addressed to every client in a single board room,
+// or only to the client with ID To when it is set. The client with ID
+// Except, usually the sender, does not get it.
+//
+// Ephemeral messages also carry a Key, a later message with the same Key
+// replaces one that has not been flushed yet.
+type Message struct {
+	Board  string
+	To     string
+	Except string
+	Key    string
+	Data   []byte
+}
+
+// FlushInterval is how often the ephemeral messages of a room are merged
+// into a single batch and sent out
+const FlushInterval = 50 * time.Millisecond
+
+// pendingRoom holds the ephemeral messages of a room until the next flush,
+// in the order their keys first appeared
+type pendingRoom struct {
+	keys     []string
+	messages map[string]Message
+}
+
 type Manager struct {
 	ClientList map[string]*Client
+	Rooms      map[string]map[string]*Client
 	Register   chan *Client
 	Unregister chan *Client
-	Broadcast  chan []byte
-	Mu         sync.Mutex
+	Broadcast  chan Message
+	// Ephemeral takes cursor moves and other live-only messages, they are
+	// coalesced per room and fanned out once per FlushInterval
+	Ephemeral chan Message
+	Mu        sync.Mutex
+
+	pending map[string]*pendingRoom
 }
 
 func NewManager() *Manager {
 	return &Manager{
 		ClientList: make(map[string]*Client),
+		Rooms:      make(map[string]map[string]*Client),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
-		Broadcast:  make(chan []byte),
+		Broadcast:  make(chan Message),
+		Ephemeral:  make(chan Message, 256),
 		Mu:         sync.Mutex{},
+		pending:    make(map[string]*pendingRoom),
 	}
 }
 
 func (m *Manager) Run() {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		//register for a new client
 		case client := <-m.Register:
 			m.Mu.Lock()
 			m.ClientList[client.ID] = client
+			room, ok := m.Rooms[client.Board]
+			if !ok {
+				room = make(map[string]*Client)
+				m.Rooms[client.Board] = room
+			}
+			room[client.ID] = client
+			m.announceJoin(client)
 			m.Mu.Unlock()
 		//unregister client
 		case client := <-m.Unregister:
 			m.Mu.Lock()
 			if _, ok := m.ClientList[client.ID]; ok {
-				delete(m.ClientList, client.ID)
-				close(client.Send)
+				m.removeClient(client)
 			}
 			m.Mu.Unlock()
-		//manager sends message to all clients
+		//manager sends message to all clients of the board
 		case message := <-m.Broadcast:
 			m.Mu.Lock()
-			for _, client := range m.ClientList {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(m.ClientList, client.ID)
+			m.deliver(message)
+			m.Mu.Unlock()
+		//ephemeral messages wait for the next tick
+		case message := <-m.Ephemeral:
+			m.hold(message)
+		case <-ticker.C:
+			m.Mu.Lock()
+			m.flush()
+			m.Mu.Unlock()
+		}
+	}
+}
+
+// hold queues an ephemeral message for the next flush, replacing the pending
+// message with the same key. Only Run touches pending and writes ClientList.
+func (m *Manager) hold(message Message) {
+	// the sender may have left while its message was queued
+	if _, ok := m.ClientList[message.Except]; message.Except != "" && !ok {
+		return
+	}
+
+	room, ok := m.pending[message.Board]
+	if !ok {
+		room = &pendingRoom{messages: make(map[string]Message)}
+		m.pending[message.Board] = room
+	}
+	if _, ok := room.messages[message.Key]; !ok {
+		room.keys = append(room.keys, message.Key)
+	}
+	room.messages[message.Key] = message
+}
+
+// flush sends every client one batch of the ephemeral messages held for its
+// room, leaving out the ones it sent itself, caller must hold Mu
+func (m *Manager) flush() {
+	for board, pending := range m.pending {
+		delete(m.pending, board)
+
+		messages := make([]Message, 0, len(pending.keys))
+		senders := make(map[string]bool)
+		for _, key := range pending.keys {
+			message, ok := pending.messages[key]
+			if !ok {
+				continue
+			}
+			messages = append(messages, message)
+			if message.Except != "" {
+				senders[message.Except] = true
+			}
+		}
+
+		// everyone who sent nothing gets the same batch
+		var shared []byte
+		var slow []*Client
+		for _, client := range m.Rooms[board] {
+			var data []byte
+			if senders[client.ID] {
+				data = batchMessage(messages, client.ID)
+			} else {
+				if shared == nil {
+					shared = batchMessage(messages, "")
 				}
+				data = shared
 			}
-			m.Mu.Unlock()
+			if data == nil {
+				continue
+			}
+
+			select {
+			case client.Send <- data:
+			default:
+				slow = append(slow, client)
+			}
+		}
+
+		for _, client := range slow {
+			if _, ok := m.ClientList[client.ID]; ok {
+				m.removeClient(client)
+			}
+		}
+	}
+}
+
+// batchMessage merges the messages addressed to the client with ID to, a
+// single message is sent as is. It returns nil when nothing is left.
+func batchMessage(messages []Message, to string) []byte {
+	var data []json.RawMessage
+	for _, message := range messages {
+		if to != "" && message.Except == to {
+			continue
+		}
+		data = append(data, message.Data)
+	}
+
+	switch len(data) {
+	case 0:
+		return nil
+	case 1:
+		return data[0]
+	}
+	return serverMessage(models.Batch, models.BatchData{Messages: data})
+}
+
+// deliver hands the message to the send buffers of its room and drops the
+// clients that cannot keep up, caller must hold Mu
+func (m *Manager) deliver(message Message) {
+	var slow []*Client
+	for _, client := range m.Rooms[message.Board] {
+		if message.To != "" && message.To != client.ID {
+			continue
+		}
+		if message.Except != "" && message.Except == client.ID {
+			continue
+		}
+		select {
+		case client.Send <- message.Data:
+		default:
+			slow = append(slow, client)
+		}
+	}
+
+	for _, client := range slow {
+		if _, ok := m.ClientList[client.ID]; ok {
+			m.removeClient(client)
+		}
+	}
+}
+
+// removeClient drops the client from the manager and its room and tells the
+// rest of the room it left, caller must hold Mu
+func (m *Manager) removeClient(client *Client) {
+	delete(m.ClientList, client.ID)
+	if room, ok := m.Rooms[client.Board]; ok {
+		delete(room, client.ID)
+		if len(room) == 0 {
+			delete(m.Rooms, client.Board)
+		}
+	}
+	close(client.Send)
+
+	// the leave must not be followed by a cursor of the client that left
+	if pending, ok := m.pending[client.Board]; ok {
+		for key, message := range pending.messages {
+			if message.Except == client.ID {
+				delete(pending.messages, key)
+			}
+		}
+	}
+
+	m.deliver(Message{Board: client.Board, Data: serverMessage(models.PresenceLeave, client.Presence())})
+}
+
+// announceJoin sends the new client the roster of its room and tells the
+// others it joined, caller must hold Mu
+func (m *Manager) announceJoin(client *Client) {
+	roster := models.RosterData{Clients: make([]models.PresenceData, 0, len(m.Rooms[client.Board]))}
+	for _, c := range m.Rooms[client.Board] {
+		roster.Clients = append(roster.Clients, c.Presence())
+	}
+
+	m.deliver(Message{Board: client.Board, To: client.ID, Data: serverMessage(models.PresenceRoster, roster)})
+	m.deliver(Message{Board: client.Board, Except: client.ID, Data: serverMessage(models.PresenceJoin, client.Presence())})
+}
+
+func serverMessage(msgType models.MessageType, data interface{}) []byte {
+	payload, err := json.Marshal(models.ServerMessage{Type: msgType, Data: data})
+	if err != nil {
+		logger.Error("Encoding Error: %s", err)
+	}
+	return payload
+}
+
+// Disconnect closes the connections of userID to the board, the clients
+// reconnect with whatever access the user has left
+func (m *Manager) Disconnect(board string, userID string) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	for _, client := range m.Rooms[board] {
+		if client.UserID == userID {
+			client.Conn.Close()
+		}
+	}
+}
+
+// DisconnectAll closes every connection to the board, clients that reconnect
+// join again with the access they still have
+func (m *Manager) DisconnectAll(board string) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	for _, client := range m.Rooms[board] {
+		client.Conn.Close()
+	}
+}
+
+// DisconnectUser closes every connection of userID, on any board
+func (m *Manager) DisconnectUser(userID string) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	for _, client := range m.ClientList {
+		if client.UserID == userID {
+			client.Conn.Close()
+		}
+	}
+}
+
+// DisconnectSession closes the connections opened with tokens of the session
+func (m *Manager) DisconnectSession(userID string, sessionID string) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	for _, client := range m.ClientList {
+		if client.UserID == userID && client.SessionID == sessionID {
+			client.Conn.Close()
 		}
 	}
 }
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const inviteAudience = "board-invite"
+
+// InviteClaims grant Role on Board to whoever redeems the token,
+// ID is the id of the stored invite so it can be revoked
+type InviteClaims struct {
+	Board string `json:"board"`
+	Role  string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// inviteKey is derived from the JWT secret, or the signing key without one,
+// so an invite never verifies as an auth token
+func inviteKey() ([]byte, error) {
+	ks, err := keys()
+	if err != nil {
+		return nil, err
+	}
+	return ks.inviteKey, nil
+}
+
+func CreateInviteToken(inviteID string, boardID string, role string, expiresAt time.Time) (string, error) {
+	claims := InviteClaims{
+		Board: boardID,
+		Role:  role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        inviteID,
+			Issuer:    "shared-drawboard",
+			Audience:  jwt.ClaimStrings{inviteAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	key, err := inviteKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(key)
+}
+
+func VerifyInviteToken(tokenStr string) (*InviteClaims, error) {
+	key, err := inviteKey()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &InviteClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key, nil
+	}, jwt.WithAudience(inviteAudience), jwt.WithExpirationRequired())
+
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid or expired invite: %w", err)
+	}
+	if claims.ID == "" || claims.Board == "" {
+		return nil, fmt.Errorf("invalid invite claims")
+	}
+	return claims, nil
+}
@@ -17,39 +17,41 @@ func setEnvVariables() {
 	}
 }
 
-func CreateJWTToken(username string, expiryTime int64) (string, error) {
-	setEnvVariables()
-
-	secretKey := os.Getenv("SECRETKEY_FOR_JWT")
+// CreateJWTToken issues an auth token for the user, sid ties it to the
+// refresh session it was issued for so logging out can end it
+func CreateJWTToken(username string, sessionID string, expiryTime int64) (string, error) {
+	ks, err := keys()
+	if err != nil {
+		return "", err
+	}
 
 	claims := jwt.MapClaims{
 		"sub": username,
+		"sid": sessionID,
 		// "role": role,
 		"iss": "shared-drawboard",
 		"exp": expiryTime,
 		"iat": time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString([]byte(secretKey))
-	if err != nil {
-		return "", err
+	if ks.signingKey == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(ks.secret)
 	}
 
-	return tokenString, nil
+	// the kid tells verifiers which of the published keys to use
+	token := jwt.NewWithClaims(ks.signingMethod, claims)
+	token.Header["kid"] = ks.signingKID
+	return token.SignedString(ks.signingKey)
 }
 
 func VerifyJWTToken(tokenStr string) (jwt.Claims, error) {
-	setEnvVariables()
-	secretKey := os.Getenv("SECRETKEY_FOR_JWT")
+	ks, err := keys()
+	if err != nil {
+		return jwt.MapClaims{}, err
+	}
 
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secretKey), nil
-	})
+	token, err := jwt.Parse(tokenStr, ks.keyFunc, jwt.WithExpirationRequired())
 
 	if err != nil || !token.Valid {
 		return jwt.MapClaims{}, fmt.Errorf("invalid or expired token: %w", err)
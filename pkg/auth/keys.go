@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted for signing or verifying
+const minRSABits = 2048
+
+// HS256Grace is how long after startup HS256 tokens are still accepted once a
+// signing key is configured, the lifetime of an auth token. Users signed in
+// before the switch keep working until their next refresh.
+const HS256Grace = 15 * time.Minute
+
+// verifyKey is a public key and the only algorithm tokens signed with it may use
+type verifyKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// keySet is the JWT configuration. Without a signing key, auth tokens are
+// signed and verified with HS256 and the secret alone.
+type keySet struct {
+	// SECRETKEY_FOR_JWT, only required without a signing key
+	secret []byte
+	// signs invite links, derived from the secret or else the signing key
+	inviteKey []byte
+	// HS256 tokens signed with the secret are accepted until then, see HS256Grace
+	hs256Until time.Time
+
+	signingKID    string
+	signingKey    interface{}
+	signingMethod jwt.SigningMethod
+	// every key in JWT_KEYS_DIR by kid, including the signing key
+	verify map[string]verifyKey
+}
+
+var (
+	keysOnce   sync.Once
+	loadedKeys *keySet
+	keysErr    error
+)
+
+// LoadKeys reads the keys once, later calls return the first result.
+//
+// JWT_KEYS_DIR holds one PEM file per key named <kid>.pem, either an RSA or
+// Ed25519 private key, or only the public key of a key that was rotated out
+// but whose tokens are still valid. JWT_SIGNING_KID names the private key
+// new tokens are signed with.
+func LoadKeys() error {
+	keysOnce.Do(func() {
+		setEnvVariables()
+		loadedKeys, keysErr = readKeys(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_SIGNING_KID"), os.Getenv("SECRETKEY_FOR_JWT"))
+	})
+	return keysErr
+}
+
+func keys() (*keySet, error) {
+	if err := LoadKeys(); err != nil {
+		return nil, err
+	}
+	return loadedKeys, nil
+}
+
+func readKeys(dir string, signingKID string, secret string) (*keySet, error) {
+	ks := &keySet{verify: make(map[string]verifyKey)}
+	if secret != "" {
+		ks.secret = []byte(secret)
+		ks.inviteKey = deriveKey("invite:", ks.secret)
+	}
+
+	if dir == "" {
+		if signingKID != "" {
+			return nil, errors.New("JWT_SIGNING_KID is set without JWT_KEYS_DIR")
+		}
+		if secret == "" {
+			return nil, errors.New("SECRETKEY_FOR_JWT is not set")
+		}
+		return ks, nil
+	}
+	if signingKID == "" {
+		return nil, errors.New("JWT_KEYS_DIR is set without JWT_SIGNING_KID")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		public, private, err := parseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+		method, err := methodFor(public)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		ks.verify[kid] = verifyKey{method: method, key: public}
+		if kid == signingKID {
+			if private == nil {
+				return nil, fmt.Errorf("signing key %s is not a private key", kid)
+			}
+			ks.signingKID = kid
+			ks.signingKey = private
+			ks.signingMethod = method
+		}
+	}
+
+	if ks.signingKey == nil {
+		return nil, fmt.Errorf("signing key %s not found in %s", signingKID, dir)
+	}
+
+	if ks.secret != nil {
+		ks.hs256Until = time.Now().Add(HS256Grace)
+	} else {
+		// invites end when the signing key changes, the secret keeps them valid
+		der, err := x509.MarshalPKCS8PrivateKey(ks.signingKey)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", signingKID, err)
+		}
+		ks.inviteKey = deriveKey("invite:", der)
+	}
+	return ks, nil
+}
+
+// deriveKey returns a key for one purpose, so it never verifies another kind of token
+func deriveKey(purpose string, secret []byte) []byte {
+	key := sha256.Sum256(append([]byte(purpose), secret...))
+	return key[:]
+}
+
+// parseKey reads a PEM encoded key, private is nil for a public key
+func parseKey(data []byte) (public interface{}, private interface{}, err error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM data")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch key := private.(type) {
+	case nil:
+	case *rsa.PrivateKey:
+		public = &key.PublicKey
+	case ed25519.PrivateKey:
+		public = key.Public()
+	default:
+		return nil, nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+	return public, private, nil
+}
+
+// methodFor returns RS256 for RSA keys and EdDSA for Ed25519 keys
+func methodFor(public interface{}) (jwt.SigningMethod, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must have at least %d bits", minRSABits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", public)
+}
+
+// keyFunc picks the key a token must be signed with, by its kid header. The
+// algorithm always comes from the key, never from the token.
+func (ks *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if ks.signingKey == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && kid == "" {
+		// issued before the switch to the signing key
+		if ks.secret == nil || !time.Now().Before(ks.hs256Until) {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return ks.secret, nil
+	}
+
+	key, ok := ks.verify[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.key, nil
+}
+
+// JWK is a public verification key as published in the JWKS document
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKeys lists every key auth tokens may be signed with, it is empty
+// while tokens are signed with the shared secret
+func PublicKeys() (JWKS, error) {
+	ks, err := keys()
+	if err != nil {
+		return JWKS{}, err
+	}
+
+	set := JWKS{Keys: make([]JWK, 0, len(ks.verify))}
+	for kid, key := range ks.verify {
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.key.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set, nil
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKeys stores an Ed25519 key "ed" and an RSA key "rsa" as PEM files
+func writeKeys(t *testing.T) (dir string, edKey ed25519.PrivateKey, rsaKey *rsa.PrivateKey) {
+	t.Helper()
+	dir = t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err = rsa.GenerateKey(rand.Reader, minRSABits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for kid, key := range map[string]interface{}{"ed": edKey, "rsa": rsaKey} {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, edKey, rsaKey
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"sub": "user@example.com",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestKeyFunc(t *testing.T) {
+	dir, edKey, rsaKey := writeKeys(t)
+	secret := []byte("secret")
+	edPublic := []byte(edKey.Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name    string
+		secret  string
+		expired bool
+		token   string
+		wantOK  bool
+	}{
+		{"signing key", "", false, sign(t, jwt.SigningMethodEdDSA, "ed", edKey), true},
+		{"other key in the directory", "", false, sign(t, jwt.SigningMethodRS256, "rsa", rsaKey), true},
+		{"unknown kid", "", false, sign(t, jwt.SigningMethodEdDSA, "gone", edKey), false},
+		{"no kid", "", false, sign(t, jwt.SigningMethodEdDSA, "", edKey), false},
+		{"algorithm of another key", "", false, sign(t, jwt.SigningMethodRS256, "ed", rsaKey), false},
+		{"public key as HMAC secret", "", false, sign(t, jwt.SigningMethodHS256, "ed", edPublic), false},
+		{"HS256 without a secret", "", false, sign(t, jwt.SigningMethodHS256, "", secret), false},
+		{"HS256 during the migration", "secret", false, sign(t, jwt.SigningMethodHS256, "", secret), true},
+		{"HS256 after the migration", "secret", true, sign(t, jwt.SigningMethodHS256, "", secret), false},
+		{"HS256 with a kid", "secret", false, sign(t, jwt.SigningMethodHS256, "ed", secret), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := readKeys(dir, "ed", tt.secret)
+			if err != nil {
+				t.Fatalf("readKeys: %v", err)
+			}
+			if tt.expired {
+				ks.hs256Until = time.Now().Add(-time.Second)
+			}
+
+			_, err = jwt.Parse(tt.token, ks.keyFunc, jwt.WithExpirationRequired())
+			if ok := err == nil; ok != tt.wantOK {
+				t.Errorf("token accepted = %v, want %v (%v)", ok, tt.wantOK, err)
+			}
+		})
+	}
+}
+
+func TestReadKeysSecret(t *testing.T) {
+	dir, _, _ := writeKeys(t)
+
+	if _, err := readKeys("", "", ""); err == nil {
+		t.Error("readKeys accepted neither a secret nor a signing key")
+	}
+
+	withSecret, err := readKeys(dir, "ed", "secret")
+	if err != nil {
+		t.Fatalf("readKeys with a secret: %v", err)
+	}
+	hs256Only, err := readKeys("", "", "secret")
+	if err != nil {
+		t.Fatalf("readKeys with only a secret: %v", err)
+	}
+	// invites created before the switch to a signing key stay valid
+	if string(withSecret.inviteKey) != string(hs256Only.inviteKey) {
+		t.Error("the signing key changed the invite key derived from the secret")
+	}
+
+	ks, err := readKeys(dir, "ed", "")
+	if err != nil {
+		t.Fatalf("readKeys without a secret: %v", err)
+	}
+	if len(ks.inviteKey) == 0 {
+		t.Fatal("no invite key without a secret")
+	}
+	if string(ks.inviteKey) == string(withSecret.inviteKey) {
+		t.Error("invite key without a secret is the one derived from the secret")
+	}
+}
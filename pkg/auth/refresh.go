@@ -2,7 +2,9 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 )
 
 func CreateRefreshToken(size int) (string, error) {
@@ -12,3 +14,10 @@ func CreateRefreshToken(size int) (string, error) {
 	}
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
+
+// HashRefreshToken is what is stored of a refresh token. The tokens are long
+// and random, so a fast hash that can be looked up is enough.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/shared-drawboard/internal/models"
@@ -14,7 +13,127 @@ func GenerateUniqueID() string {
 	return fmt.Sprintf("client-%d", time.Now().UnixNano())
 }
 
+const maxObjectIDLength = 64
+
+var ErrInvalidObjectID = errors.New("object id must be 1-64 characters of letters, digits, '-' or '_'")
+
+// ValidateObjectID checks the client generated id of a drawable
+func ValidateObjectID(id string) error {
+	if id == "" || len(id) > maxObjectIDLength {
+		return ErrInvalidObjectID
+	}
+	for _, c := range id {
+		isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isDigit := c >= '0' && c <= '9'
+		if !isLetter && !isDigit && c != '-' && c != '_' {
+			return ErrInvalidObjectID
+		}
+	}
+	return nil
+}
+
+func validateEventObjectID(id string, legacy bool) error {
+	if legacy && id == "" {
+		return nil
+	}
+	return ValidateObjectID(id)
+}
+
+// validateObjectUpdate rejects updates that change nothing or set invalid values
+func validateObjectUpdate(data models.ObjectUpdateData) error {
+	if data.DX == 0 && data.DY == 0 && data.X == nil && data.Y == nil &&
+		data.Width == nil && data.Height == nil && data.Color == nil &&
+		data.Thickness == nil && data.Text == nil {
+		return errors.New("object update has no changes")
+	}
+	if data.Thickness != nil && *data.Thickness <= 0 {
+		return errors.New("thickness must be positive")
+	}
+	if data.Color != nil && *data.Color == "" {
+		return errors.New("color must not be empty")
+	}
+	if data.Text != nil && *data.Text == "" {
+		return errors.New("text must not be empty")
+	}
+	return nil
+}
+
+// PeekMessageType reads only the type of an incoming websocket message
+func PeekMessageType(rawData []byte) (string, error) {
+	var base struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(rawData, &base); err != nil {
+		return "", err
+	}
+	return base.Type, nil
+}
+
+func ParseSyncRequest(rawData []byte) (models.SyncRequestData, error) {
+	var req struct {
+		Data models.SyncRequestData `json:"data"`
+	}
+	if err := json.Unmarshal(rawData, &req); err != nil {
+		return models.SyncRequestData{}, err
+	}
+	if req.Data.Since < 0 {
+		return models.SyncRequestData{}, errors.New("since must not be negative")
+	}
+	return req.Data, nil
+}
+
+// ParseCursor reads a cursor position, the sender is filled in by the server
+func ParseCursor(rawData []byte) (models.CursorData, error) {
+	var req struct {
+		Data models.CursorData `json:"data"`
+	}
+	if err := json.Unmarshal(rawData, &req); err != nil {
+		return models.CursorData{}, err
+	}
+	return models.CursorData{X: req.Data.X, Y: req.Data.Y}, nil
+}
+
+// MaxStrokeMessagePoints is how many points a single stroke message may carry
+const MaxStrokeMessagePoints = 1000
+
+// ParseStroke reads a piece of a stroke in progress, the sender is filled in by the server
+func ParseStroke(rawData []byte) (models.StrokeData, error) {
+	var req struct {
+		Tool string            `json:"tool"`
+		Data models.StrokeData `json:"data"`
+	}
+	if err := json.Unmarshal(rawData, &req); err != nil {
+		return models.StrokeData{}, err
+	}
+	if err := ValidateObjectID(req.Data.ID); err != nil {
+		return models.StrokeData{}, err
+	}
+	if len(req.Data.Points) > MaxStrokeMessagePoints {
+		return models.StrokeData{}, errors.New("too many points in one stroke message")
+	}
+
+	stroke := req.Data
+	stroke.ClientID = ""
+	if stroke.Tool == "" {
+		stroke.Tool = req.Tool
+	}
+	return stroke, nil
+}
+
+// ParseEventData decodes an event sent by a client, every drawable it
+// refers to needs a valid id
 func ParseEventData(rawData []byte) (models.Event, error) {
+	return parseEventData(rawData, false)
+}
+
+// ParseLegacyEventData decodes an event stored before drawables had ids. An
+// empty id is accepted and refers to no object, except that a delete without
+// an id addresses the object by its index.
+func ParseLegacyEventData(rawData []byte) (models.Event, error) {
+	return parseEventData(rawData, true)
+}
+
+func parseEventData(rawData []byte, legacy bool) (models.Event, error) {
 	// First pass to get event type
 	var baseEvent struct {
 		Type models.EventType `json:"type"`
@@ -29,7 +148,6 @@ func ParseEventData(rawData []byte) (models.Event, error) {
 	var event models.Event
 	event.Type = baseEvent.Type
 	event.Tool = baseEvent.Tool
-	event.CreatedAt = strconv.FormatInt(time.Now().Unix(), 10)
 
 	// Second pass for specific data types
 	switch baseEvent.Type {
@@ -38,6 +156,9 @@ func ParseEventData(rawData []byte) (models.Event, error) {
 		if err := json.Unmarshal(baseEvent.Data, &data); err != nil {
 			return models.Event{}, err
 		}
+		if err := validateEventObjectID(data.ID, legacy); err != nil {
+			return models.Event{}, err
+		}
 		event.Data = data
 
 	case models.ShapeCreate:
@@ -45,6 +166,9 @@ func ParseEventData(rawData []byte) (models.Event, error) {
 		if err := json.Unmarshal(baseEvent.Data, &data); err != nil {
 			return models.Event{}, err
 		}
+		if err := validateEventObjectID(data.ID, legacy); err != nil {
+			return models.Event{}, err
+		}
 		event.Data = data
 
 	case models.TextAdd:
@@ -52,6 +176,9 @@ func ParseEventData(rawData []byte) (models.Event, error) {
 		if err := json.Unmarshal(baseEvent.Data, &data); err != nil {
 			return models.Event{}, err
 		}
+		if err := validateEventObjectID(data.ID, legacy); err != nil {
+			return models.Event{}, err
+		}
 		event.Data = data
 
 	case models.ObjectDelete:
@@ -59,6 +186,26 @@ func ParseEventData(rawData []byte) (models.Event, error) {
 		if err := json.Unmarshal(baseEvent.Data, &data); err != nil {
 			return models.Event{}, err
 		}
+		if err := validateEventObjectID(data.ID, legacy); err != nil {
+			return models.Event{}, err
+		}
+		if data.ID != "" {
+			// only legacy deletes address objects by index
+			data.Index = nil
+		}
+		event.Data = data
+
+	case models.ObjectUpdate:
+		var data models.ObjectUpdateData
+		if err := json.Unmarshal(baseEvent.Data, &data); err != nil {
+			return models.Event{}, err
+		}
+		if err := validateEventObjectID(data.ID, legacy); err != nil {
+			return models.Event{}, err
+		}
+		if err := validateObjectUpdate(data); err != nil {
+			return models.Event{}, err
+		}
 		event.Data = data
 
 	case models.BoardClear:
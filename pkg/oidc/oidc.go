@@ -0,0 +1,346 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// maxResponseSize caps what is read from the identity provider
+	maxResponseSize = 1 << 20
+	// keysRefetchInterval is how often an unknown kid may trigger a JWKS fetch
+	keysRefetchInterval = time.Minute
+	// clockSkew is how far the provider's clock may be off from ours
+	clockSkew = time.Minute
+)
+
+var (
+	ErrEmailMissing    = errors.New("identity provider did not return an email")
+	ErrEmailUnverified = errors.New("email is not verified by the identity provider")
+)
+
+// Config is the identity provider and the client registered with it
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// where the provider sends the browser back to, /auth/oidc/callback
+	RedirectURL string
+	Scopes      []string
+}
+
+// ConfigFromEnv reads OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET,
+// OIDC_REDIRECT_URL and the optional OIDC_SCOPES. It returns nil when
+// OIDC_ISSUER is not set.
+func ConfigFromEnv() (*Config, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+
+	cfg := &Config{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+	if scopes := os.Getenv("OIDC_SCOPES"); scopes != "" {
+		cfg.Scopes = strings.Fields(scopes)
+	}
+
+	if cfg.ClientID == "" {
+		return nil, errors.New("OIDC_ISSUER is set without OIDC_CLIENT_ID")
+	}
+	if cfg.RedirectURL == "" {
+		return nil, errors.New("OIDC_ISSUER is set without OIDC_REDIRECT_URL")
+	}
+	return cfg, nil
+}
+
+// Identity is the user the provider signed in
+type Identity struct {
+	Subject string
+	Email   string
+	Name    string
+}
+
+// discovery is the part of the provider metadata the login flow needs
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider runs the authorization code flow against one issuer. The
+// metadata and keys are fetched on first use, so the server starts even
+// while the provider is unreachable.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu          sync.Mutex
+	metadata    *discovery
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+func NewProvider(cfg Config) *Provider {
+	return &Provider{
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewState returns a random value for the state, nonce or PKCE verifier of a login
+func NewState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthCodeURL is where the browser is sent to sign in. The verifier stays
+// with the browser's login state, only its S256 challenge is sent.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return md.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades the code from the callback for an id token and returns
+// the identity in it once the token is verified
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// client_secret_basic encodes both parts before joining them
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.fetch(req, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("token request failed: %s", strings.TrimSpace(tokens.Error+" "+tokens.ErrorDescription))
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("token request failed: status %d", status)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.verify(ctx, md, tokens.IDToken, nonce)
+}
+
+// idTokenClaims are the claims of the id token the login uses
+type idTokenClaims struct {
+	Nonce string `json:"nonce"`
+	Azp   string `json:"azp"`
+	Email string `json:"email"`
+	// a bool, some providers send it as a string
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+	jwt.RegisteredClaims
+}
+
+func (p *Provider) verify(ctx context.Context, md *discovery, idToken string, nonce string) (*Identity, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(idToken, &claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.key(ctx, md, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(md.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("invalid id token: nonce does not match")
+	}
+	if len(claims.Audience) > 1 && claims.Azp != p.config.ClientID {
+		return nil, errors.New("invalid id token: issued to another client")
+	}
+
+	if claims.Email == "" {
+		return nil, ErrEmailMissing
+	}
+	// an email the provider does not vouch for could take over an existing account
+	if !emailVerified(claims.EmailVerified) {
+		return nil, ErrEmailUnverified
+	}
+
+	return &Identity{Subject: claims.Subject, Email: claims.Email, Name: claims.Name}, nil
+}
+
+// emailVerified reports whether the email_verified claim is true, a missing
+// claim counts as unverified
+func emailVerified(claim interface{}) bool {
+	switch verified := claim.(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
+
+// discover fetches the provider metadata once, a failed fetch is retried on the next login
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var md discovery
+	status, err := p.fetch(req, &md)
+	if err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery failed: status %d", status)
+	}
+
+	// the issuer in the tokens has to be the one that was configured
+	if strings.TrimSuffix(md.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("discovery failed: issuer %q does not match %q", md.Issuer, p.config.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, errors.New("discovery failed: missing endpoints")
+	}
+
+	p.metadata = &md
+	return p.metadata, nil
+}
+
+// key returns the provider's public key with the kid. Keys are fetched again
+// when the kid is unknown, the provider may have rotated them.
+func (p *Provider) key(ctx context.Context, md *discovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < keysRefetchInterval {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, md.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set jwks
+	status, err := p.fetch(req, &set)
+	if err != nil {
+		return nil, fmt.Errorf("fetching keys failed: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("fetching keys failed: status %d", status)
+	}
+
+	p.keys = set.publicKeys()
+	p.keysFetched = time.Now()
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// lookup finds the key with the kid, a token without a kid may only be
+// verified when the provider has a single key
+func (p *Provider) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// fetch sends the request and decodes the JSON body into v, whatever the status
+func (p *Provider) fetch(req *http.Request, v interface{}) (int, error) {
+	res, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && res.StatusCode == http.StatusOK {
+		return 0, err
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,26 @@
+package oidc
+
+import "testing"
+
+func TestEmailVerified(t *testing.T) {
+	tests := []struct {
+		name  string
+		claim interface{}
+		want  bool
+	}{
+		{"true", true, true},
+		{"false", false, false},
+		{"string true", "true", true},
+		{"string false", "false", false},
+		{"missing", nil, false},
+		{"number", 1.0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := emailVerified(tt.claim); got != tt.want {
+				t.Errorf("emailVerified(%v) = %v, want %v", tt.claim, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+// script.js
+// Lists the boards owned by or shared with the signed in user
+const boardList = document.getElementById('board-list');
+const createForm = document.getElementById('create-board-form');
+const showArchived = document.getElementById('show-archived');
+
+document.addEventListener('DOMContentLoaded', () => {
+    // Invite links point here, keep the token across a detour to the login page
+    const invite = new URLSearchParams(window.location.search).get('invite');
+    if (invite) {
+        localStorage.setItem('pending-invite', invite);
+        history.replaceState(null, '', '/dashboard/');
+    }
+    if (localStorage.getItem('pending-invite')) {
+        redeemInvite();
+    } else {
+        loadBoards();
+    }
+});
+
+async function redeemInvite() {
+    const token = localStorage.getItem('pending-invite');
+    const res = await apiFetch('/invites/redeem', {
+        method: 'POST',
+        body: JSON.stringify({ token }),
+    });
+    if (!res) return;
+
+    localStorage.removeItem('pending-invite');
+    if (res.ok) {
+        const board = await res.json();
+        window.location.href = `/drawboard/?board=${encodeURIComponent(board._id)}`;
+        return;
+    }
+    loadBoards();
+}
+
+createForm.addEventListener('submit', async (e) => {
+    e.preventDefault();
+    const name = document.getElementById('board-name').value.trim();
+    if (!name) return;
+
+    const res = await apiFetch('/boards', {
+        method: 'POST',
+        body: JSON.stringify({ name }),
+    });
+    if (res && res.ok) {
+        createForm.reset();
+        loadBoards();
+    }
+});
+
+showArchived.addEventListener('change', () => loadBoards());
+
+document.getElementById('logout').addEventListener('click', () => logout('/logout'));
+document.getElementById('logout-all').addEventListener('click', () => {
+    if (confirm('Log out on every device?')) logout('/logout/all');
+});
+
+const sessionList = document.getElementById('session-list');
+
+document.getElementById('show-sessions').addEventListener('click', () => {
+    sessionList.hidden = !sessionList.hidden;
+    if (!sessionList.hidden) loadSessions();
+});
+
+// Every device the user is signed in on
+async function loadSessions() {
+    const res = await apiFetch('/sessions');
+    if (!res || !res.ok) return;
+
+    const data = await res.json();
+    sessionList.innerHTML = '';
+    data.sessions.forEach(session => {
+        const item = document.createElement('li');
+        item.className = 'board-card';
+
+        const device = document.createElement('strong');
+        device.textContent = session.user_agent || 'Unknown device';
+        item.appendChild(device);
+
+        const details = document.createElement('span');
+        details.className = 'board-role';
+        const lastUsed = new Date(parseInt(session.last_used_at, 10) * 1000).toLocaleString();
+        details.textContent = `${session.ip} · last used ${lastUsed}`;
+        item.appendChild(details);
+
+        const actions = document.createElement('div');
+        actions.className = 'board-actions';
+        if (session.current) {
+            const current = document.createElement('span');
+            current.className = 'board-role';
+            current.textContent = 'This device';
+            actions.appendChild(current);
+        } else {
+            actions.appendChild(actionButton('🚫 Sign out', () => revokeSession(session), 'danger'));
+        }
+        item.appendChild(actions);
+
+        sessionList.appendChild(item);
+    });
+}
+
+async function revokeSession(session) {
+    if (!confirm(`Sign out ${session.user_agent || 'this device'}?`)) return;
+
+    const res = await apiFetch(`/sessions/${session._id}`, { method: 'DELETE' });
+    if (res && res.ok) loadSessions();
+}
+
+// Ends the session on the server, which also clears the refresh cookies
+async function logout(url) {
+    const res = await apiFetch(url, { method: 'POST' });
+    if (!res || !res.ok) return;
+
+    localStorage.removeItem('auth-token');
+    localStorage.removeItem('token-expiry');
+    window.location.href = '/login/';
+}
+
+async function fetchToken() {
+    const token = localStorage.getItem('auth-token');
+    const expiry = parseInt(localStorage.getItem('token-expiry'), 10);
+    if (token && expiry && Date.now() < expiry) {
+        return token;
+    }
+
+    const refreshed = await refreshAccessToken();
+    if (refreshed) {
+        return localStorage.getItem('auth-token');
+    }
+    return null;
+}
+
+async function refreshAccessToken() {
+    try {
+        const res = await fetch('/refresh', {
+            method: 'POST',
+            credentials: 'include', // send the refresh token cookie and also the user-id cookie
+        });
+
+        if (!res.ok) return false;
+
+        const data = await res.json();
+        localStorage.setItem('auth-token', data["auth-token"]);
+        localStorage.setItem('token-expiry', Date.now() + 15 * 60 * 1000);
+        return true;
+    } catch (error) {
+        console.error("Refresh token request failed:", error);
+        return false;
+    }
+}
+
+async function apiFetch(url, options = {}) {
+    const token = await fetchToken();
+    if (!token) {
+        window.location.href = '/login/';
+        return null;
+    }
+
+    const res = await fetch(url, {
+        ...options,
+        headers: {
+            'Content-Type': 'application/json',
+            'Authorization': `Bearer ${token}`,
+            ...(options.headers || {}),
+        },
+    });
+    if (!res.ok) {
+        setMessage(await res.text());
+    } else {
+        setMessage('');
+    }
+    return res;
+}
+
+// The user id is the subject of the auth token
+function currentUserId() {
+    const payload = localStorage.getItem('auth-token').split('.')[1];
+    return JSON.parse(atob(payload.replace(/-/g, '+').replace(/_/g, '/'))).sub;
+}
+
+async function loadBoards() {
+    const res = await apiFetch(`/boards?archived=${showArchived.checked}`);
+    if (!res || !res.ok) return;
+
+    const data = await res.json();
+    renderBoards(data.boards || []);
+}
+
+function renderBoards(boards) {
+    boardList.innerHTML = '';
+    if (boards.length === 0) {
+        const empty = document.createElement('li');
+        empty.textContent = 'No boards yet. Create one to start drawing.';
+        boardList.appendChild(empty);
+        return;
+    }
+
+    boards.forEach(board => {
+        const item = document.createElement('li');
+        item.className = 'board-card' + (board.archived ? ' archived' : '');
+
+        const link = document.createElement('a');
+        link.textContent = board.name;
+        link.href = `/drawboard/?board=${encodeURIComponent(board._id)}`;
+        item.appendChild(link);
+
+        const actions = document.createElement('div');
+        actions.className = 'board-actions';
+        if (board.role === 'owner') {
+            actions.appendChild(actionButton('👥 Share', () => shareBoard(board)));
+            actions.appendChild(actionButton('🔗 Invite link', () => createInviteLink(board)));
+            actions.appendChild(actionButton(board.public ? '🔒 Make private' : '🌐 Make public', () => togglePublic(board)));
+            actions.appendChild(actionButton('✏️ Rename', () => renameBoard(board)));
+            actions.appendChild(actionButton(board.archived ? '📤 Unarchive' : '📥 Archive', () => archiveBoard(board)));
+            actions.appendChild(actionButton('🗑️ Delete', () => deleteBoard(board), 'danger'));
+        } else {
+            const role = document.createElement('span');
+            role.className = 'board-role';
+            role.textContent = `Shared by ${board.owner_id} · ${board.role}`;
+            actions.appendChild(role);
+            actions.appendChild(actionButton('🚪 Leave', () => leaveBoard(board)));
+        }
+        item.appendChild(actions);
+
+        boardList.appendChild(item);
+    });
+}
+
+function actionButton(label, onClick, extraClass) {
+    const btn = document.createElement('button');
+    btn.className = 'btn' + (extraClass ? ` ${extraClass}` : '');
+    btn.textContent = label;
+    btn.addEventListener('click', onClick);
+    return btn;
+}
+
+async function renameBoard(board) {
+    const name = prompt('New board name:', board.name);
+    if (!name || name === board.name) return;
+
+    const res = await apiFetch(`/boards/${board._id}`, {
+        method: 'PATCH',
+        body: JSON.stringify({ name }),
+    });
+    if (res && res.ok) loadBoards();
+}
+
+async function archiveBoard(board) {
+    const res = await apiFetch(`/boards/${board._id}`, {
+        method: 'PATCH',
+        body: JSON.stringify({ archived: !board.archived }),
+    });
+    if (res && res.ok) loadBoards();
+}
+
+async function shareBoard(board) {
+    const email = prompt('Share with (email):');
+    if (!email) return;
+    const role = prompt('Role (editor or viewer):', 'editor');
+    if (!role) return;
+
+    const res = await apiFetch(`/boards/${board._id}/members/${encodeURIComponent(email.trim())}`, {
+        method: 'PUT',
+        body: JSON.stringify({ role: role.trim().toLowerCase() }),
+    });
+    if (res && res.ok) setMessage(`Shared "${board.name}" with ${email.trim()}`);
+}
+
+async function createInviteLink(board) {
+    const role = prompt('Role granted by the link (editor or viewer):', 'viewer');
+    if (!role) return;
+
+    const res = await apiFetch(`/boards/${board._id}/invites`, {
+        method: 'POST',
+        body: JSON.stringify({ role: role.trim().toLowerCase() }),
+    });
+    if (!res || !res.ok) return;
+
+    const invite = await res.json();
+    const link = `${window.location.origin}/dashboard/?invite=${encodeURIComponent(invite.token)}`;
+    prompt('Share this link, it expires in 7 days:', link);
+}
+
+async function togglePublic(board) {
+    const res = await apiFetch(`/boards/${board._id}`, {
+        method: 'PATCH',
+        body: JSON.stringify({ public: !board.public }),
+    });
+    if (!res || !res.ok) return;
+
+    if (!board.public) {
+        const link = `${window.location.origin}/drawboard/?board=${encodeURIComponent(board._id)}&public=1`;
+        prompt('Anyone with this link can watch the board:', link);
+    }
+    loadBoards();
+}
+
+async function leaveBoard(board) {
+    if (!confirm(`Leave "${board.name}"?`)) return;
+
+    const res = await apiFetch(`/boards/${board._id}/members/${encodeURIComponent(currentUserId())}`, { method: 'DELETE' });
+    if (res && res.ok) loadBoards();
+}
+
+async function deleteBoard(board) {
+    if (!confirm(`Delete "${board.name}" and its whole drawing?`)) return;
+
+    const res = await apiFetch(`/boards/${board._id}`, { method: 'DELETE' });
+    if (res && res.ok) loadBoards();
+}
+
+function setMessage(message) {
+    document.getElementById('message').textContent = message;
+}
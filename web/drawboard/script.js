@@ -12,12 +12,43 @@ class Whiteboard {
         this.eraserSize = 20;
         this.isDrawing = false;
         this.isResizing = false;
+        this.isMoving = false;
+        this.moveDX = 0;
+        this.moveDY = 0;
         this.startX = 0;
         this.startY = 0;
         this.currentObject = null;
         this.selectedObject = null;
         this.objects = [];
         this.resizeHandleIndex = -1;
+        const params = new URLSearchParams(window.location.search);
+        this.boardId = params.get('board');
+        // Public links can be watched without signing in
+        this.publicView = params.get('public') === '1';
+        if (!this.boardId) {
+            window.location.href = "/dashboard/";
+            return;
+        }
+        
+        // Server ordering: events are applied strictly by sequence number
+        this.clientId = null;
+        this.ownClientIds = new Set();
+        this.lastSeq = 0;
+        this.queuedEvents = new Map();
+        this.syncRequested = false;
+
+        // Strokes other users are still drawing, by id
+        this.liveStrokes = new Map();
+        this.strokeSent = 0;
+        this.lastStrokeSent = 0;
+
+        // Viewers receive the board but cannot change it
+        this.readOnly = false;
+
+        // Everyone else on the board, by client id, and their pointers
+        this.peers = new Map();
+        this.cursors = new Map();
+        this.lastCursorSent = 0;
         
         // WebSocket connection
         this.ws = null;
@@ -29,30 +60,276 @@ class Whiteboard {
     async connectWebSocket() {
         // Create WebSocket connection
         const token = await this.fetchToken()
-        if(!token){
+        if(!token && !this.publicView){
             window.location.href="/login/"
+            return;
         }
 
         const host = window.location.host
-        this.ws = new WebSocket(`ws://${host}/ws?token=${token}`);
+        const auth = token ? `&token=${token}` : '';
+        // After a reconnect only the events we missed are sent back
+        const since = this.lastSeq > 0 ? `&since=${this.lastSeq}` : '';
+        const ws = new WebSocket(`ws://${host}/ws?board=${encodeURIComponent(this.boardId)}${auth}${since}`);
+        this.ws = ws;
         
         // Add WebSocket event listeners
-        this.ws.onopen = () => console.log('WebSocket connected');
-        this.ws.onmessage = (event) => this.handleWebSocketMessage(event);
-        this.ws.onclose = () => console.log('WebSocket disconnected');
-        this.ws.onerror = (error) => console.error('WebSocket error:', error);
+        ws.onopen = () => console.log('WebSocket connected');
+        ws.onmessage = (event) => this.handleWebSocketMessage(event);
+        ws.onclose = () => {
+            console.log('WebSocket disconnected');
+            // Reconnect unless this socket was replaced on purpose
+            if (this.ws === ws) {
+                this.ws = null;
+                setTimeout(() => this.connectWebSocket(), 1000);
+            }
+        };
+        ws.onerror = (error) => console.error('WebSocket error:', error);
     }
 
     handleWebSocketMessage(event) {
-        const message = JSON.parse(event.data);
+        this.handleServerMessage(JSON.parse(event.data));
+    }
+
+    handleServerMessage(message) {
         if(message.type == "TOKEN_EXPIRED"){
             this.reconnect();
+        }else if(message.type == "boardState"){
+            this.applyBoardState(message.data);
+        }else if(message.type == "resumed"){
+            this.setClientId(message.data.client_id);
+            this.setRole(message.data.role);
+            message.data.events.forEach(ev => this.handleSequencedEvent(ev));
+        }else if(message.type == "presenceRoster"){
+            this.setRoster(message.data.clients);
+        }else if(message.type == "presenceJoin"){
+            this.addPeer(message.data);
+        }else if(message.type == "presenceLeave"){
+            this.removePeer(message.data.client_id);
+        }else if(message.type == "cursor"){
+            this.moveCursor(message.data);
+        }else if(message.type == "strokeBegin"){
+            this.beginLiveStroke(message.data);
+        }else if(message.type == "strokeAppend"){
+            this.appendLiveStroke(message.data);
+        }else if(message.type == "strokeEnd"){
+            this.endLiveStroke(message.data);
+        }else if(message.type == "batch"){
+            // Cursor moves and other live updates merged by the server
+            message.data.messages.forEach(msg => this.handleServerMessage(msg));
+        }else if(message.type == "error"){
+            console.warn('Server rejected message:', message.data.message);
+        }else if(message.type == "syncEvents"){
+            this.syncRequested = false;
+            message.data.events.forEach(ev => this.handleSequencedEvent(ev));
+        }else if(message.seq){
+            // Process incoming drawing events in server order
+            this.handleSequencedEvent(message);
         }else{
-            // Process incoming drawing events from other users
             this.processRemoteEvent(message);
         }
     }
 
+    setClientId(clientId) {
+        this.clientId = clientId;
+        this.ownClientIds.add(clientId);
+    }
+
+    peerName(peer) {
+        return peer.name || (peer.anonymous ? 'Guest' : 'Member');
+    }
+
+    setRoster(clients) {
+        this.peers.clear();
+        this.cursors.forEach(el => el.remove());
+        this.cursors.clear();
+        clients.forEach(peer => {
+            if (peer.client_id !== this.clientId) this.peers.set(peer.client_id, peer);
+        });
+        this.renderPresence();
+    }
+
+    addPeer(peer) {
+        if (peer.client_id === this.clientId) return;
+        this.peers.set(peer.client_id, peer);
+        this.renderPresence();
+    }
+
+    removePeer(clientId) {
+        this.peers.delete(clientId);
+        this.liveStrokes.forEach((stroke, id) => {
+            if (stroke.clientId === clientId) this.liveStrokes.delete(id);
+        });
+        this.redrawStrokes();
+        const cursor = this.cursors.get(clientId);
+        if (cursor) {
+            cursor.remove();
+            this.cursors.delete(clientId);
+        }
+        this.renderPresence();
+    }
+
+    renderPresence() {
+        const names = [...this.peers.values()].map(peer => this.peerName(peer));
+        document.getElementById('presence').textContent = names.length
+            ? `👥 Also here: ${names.join(', ')}`
+            : '👥 Only you';
+    }
+
+    // A stable color per client so cursors are easy to tell apart
+    cursorColor(clientId) {
+        let hash = 0;
+        for (const c of clientId) hash = (hash * 31 + c.charCodeAt(0)) | 0;
+        return `hsl(${Math.abs(hash) % 360}, 70%, 45%)`;
+    }
+
+    moveCursor(data) {
+        const peer = this.peers.get(data.client_id);
+        if (!peer) return;
+
+        let cursor = this.cursors.get(data.client_id);
+        if (!cursor) {
+            cursor = document.createElement('div');
+            cursor.className = 'remote-cursor';
+            cursor.style.setProperty('--cursor-color', this.cursorColor(data.client_id));
+            const label = document.createElement('span');
+            label.textContent = this.peerName(peer);
+            cursor.appendChild(label);
+            this.canvas.parentElement.appendChild(cursor);
+            this.cursors.set(data.client_id, cursor);
+        }
+        cursor.style.left = `${data.x}px`;
+        cursor.style.top = `${data.y}px`;
+    }
+
+    // Pointer updates are throttled here and again by the server
+    sendCursor(x, y) {
+        // the server drops cursors of viewers
+        if (this.readOnly) return;
+        const now = Date.now();
+        if (now - this.lastCursorSent < 50) return;
+        this.lastCursorSent = now;
+        this.sendDrawingEvent({ type: 'cursor', data: { x, y } });
+    }
+
+    beginStroke() {
+        this.sendDrawingEvent({
+            type: 'strokeBegin',
+            tool: this.currentTool,
+            data: {
+                id: this.currentObject.id,
+                color: this.currentObject.color,
+                thickness: Number(this.currentObject.thickness),
+                points: this.currentObject.points
+            }
+        });
+        this.strokeSent = this.currentObject.points.length;
+        this.lastStrokeSent = Date.now();
+    }
+
+    // Sends the points drawn since the last message, appends at most every 50ms
+    streamStroke(type) {
+        const now = Date.now();
+        if (type === 'strokeAppend' && now - this.lastStrokeSent < 50) return;
+        const points = this.currentObject.points.slice(this.strokeSent);
+        if (type === 'strokeAppend' && points.length === 0) return;
+        this.sendDrawingEvent({ type, data: { id: this.currentObject.id, points } });
+        this.strokeSent = this.currentObject.points.length;
+        this.lastStrokeSent = now;
+    }
+
+    beginLiveStroke(data) {
+        // Pieces can arrive after the stroke was stored
+        if (this.findObjectIndex(data.id) !== -1) return;
+        this.liveStrokes.set(data.id, {
+            id: data.id,
+            clientId: data.client_id,
+            type: data.tool === 'erase' ? 'erase' : 'draw',
+            color: data.color,
+            thickness: data.thickness,
+            points: data.points || []
+        });
+        this.redrawStrokes();
+    }
+
+    appendLiveStroke(data) {
+        const stroke = this.liveStrokes.get(data.id);
+        if (!stroke) return;
+        stroke.points.push(...(data.points || []));
+        this.redrawStrokes();
+    }
+
+    endLiveStroke(data) {
+        if (this.liveStrokes.delete(data.id)) this.redrawStrokes();
+    }
+
+    // Redraws without losing the stroke this user is drawing
+    redrawStrokes() {
+        this.redraw();
+        if (this.isDrawing && this.currentObject) this.drawObject(this.currentObject);
+    }
+
+    setRole(role) {
+        if (!role) return;
+        this.readOnly = role === 'viewer';
+        document.body.classList.toggle('read-only', this.readOnly);
+        document.querySelectorAll('.toolbar button, #clear-board').forEach(btn => {
+            btn.disabled = this.readOnly;
+        });
+        if (this.readOnly) {
+            this.deselectObject();
+        }
+    }
+
+    applyBoardState(data) {
+        if (data.client_id) {
+            this.setClientId(data.client_id);
+        }
+        this.setRole(data.role);
+        this.syncRequested = false;
+        this.processRemoteEvent({ type: 'boardState', data });
+        this.lastSeq = data.seq;
+        this.drainQueuedEvents();
+    }
+
+    handleSequencedEvent(ev) {
+        if (ev.seq <= this.lastSeq) return;
+
+        if (ev.seq !== this.lastSeq + 1) {
+            // Gap detected, hold the event and ask for the missing ones
+            this.queuedEvents.set(ev.seq, ev);
+            this.requestSync();
+            return;
+        }
+
+        // Our own events, also from earlier connections, were already applied when we sent them.
+        // Undo and redo events are generated by the server and always applied.
+        if (ev.action || !this.ownClientIds.has(ev.client_id)) {
+            this.processRemoteEvent(ev);
+        }
+        this.lastSeq = ev.seq;
+        this.drainQueuedEvents();
+    }
+
+    drainQueuedEvents() {
+        for (const seq of [...this.queuedEvents.keys()]) {
+            if (seq <= this.lastSeq) this.queuedEvents.delete(seq);
+        }
+        const next = this.queuedEvents.get(this.lastSeq + 1);
+        if (next) {
+            this.queuedEvents.delete(next.seq);
+            this.handleSequencedEvent(next);
+        }
+    }
+
+    requestSync() {
+        if (this.syncRequested) return;
+        this.syncRequested = true;
+        this.sendDrawingEvent({
+            type: 'syncRequest',
+            data: { since: this.lastSeq }
+        });
+    }
+
     async fetchToken(){
         const token = localStorage.getItem("auth-token")
         const expiry = localStorage.getItem("token-expiry")
@@ -60,11 +337,11 @@ class Whiteboard {
             return token
         }
 
-        let currentToken = token
         const refreshed = await this.refreshAccessToken();
         if (refreshed) {
-            window.location.href = "/drawboard";
+            return localStorage.getItem("auth-token");
         }
+        return null;
     }
 
     async refreshAccessToken(){
@@ -87,9 +364,10 @@ class Whiteboard {
     }
 
     reconnect() {
-        this.ws.close();
+        const ws = this.ws;
         this.ws = null;
-        this.connectWS();
+        if (ws) ws.close();
+        this.connectWebSocket();
     }
 
     sendDrawingEvent(eventData) {
@@ -104,9 +382,25 @@ class Whiteboard {
         console.log('Processing remote event:', eventData);
         
         switch (eventData.type) {
+            case 'boardState':
+                // Full board sent by the server when joining
+                this.objects = eventData.data.objects.map(obj => ({
+                    ...obj,
+                    points: obj.points || [],
+                    text: obj.text || ''
+                }));
+                this.deselectObject();
+                this.redraw();
+                break;
+
             case 'freehandDraw':
+                // The finished stroke replaces its live preview
+                this.liveStrokes.delete(eventData.data.id);
+                // Our own events are echoed back, skip objects we already have
+                if (this.findObjectIndex(eventData.data.id) !== -1) break;
                 // Create a new object based on the event data
                 const drawObj = {
+                    id: eventData.data.id,
                     type: eventData.tool,
                     color: eventData.data.color,
                     thickness: eventData.data.thickness,
@@ -117,8 +411,10 @@ class Whiteboard {
                 break;
                 
             case 'shapeCreate':
+                if (this.findObjectIndex(eventData.data.id) !== -1) break;
                 // Create a new shape object based on the event data
                 const shapeObj = {
+                    id: eventData.data.id,
                     type: eventData.tool,
                     color: eventData.data.color,
                     thickness: eventData.data.thickness,
@@ -132,8 +428,10 @@ class Whiteboard {
                 break;
                 
             case 'textAdd':
+                if (this.findObjectIndex(eventData.data.id) !== -1) break;
                 // Create a new text object based on the event data
                 const textObj = {
+                    id: eventData.data.id,
                     type: 'text',
                     color: eventData.data.color,
                     thickness: eventData.data.thickness,
@@ -148,9 +446,22 @@ class Whiteboard {
                 break;
                 
             case 'objectDelete':
-                // Remove object at the specified index
-                if (eventData.data.index < this.objects.length) {
-                    this.objects.splice(eventData.data.index, 1);
+                // Remove the object with the specified id
+                const deleteIndex = this.findObjectIndex(eventData.data.id);
+                if (deleteIndex !== -1) {
+                    if (this.objects[deleteIndex] === this.selectedObject) {
+                        this.selectedObject = null;
+                    }
+                    this.objects.splice(deleteIndex, 1);
+                    this.redraw();
+                }
+                break;
+                
+            case 'objectUpdate':
+                // Move, resize or restyle the object with the specified id
+                const updateIndex = this.findObjectIndex(eventData.data.id);
+                if (updateIndex !== -1) {
+                    this.applyObjectUpdate(this.objects[updateIndex], eventData.data);
                     this.redraw();
                 }
                 break;
@@ -167,6 +478,45 @@ class Whiteboard {
         }
     }
 
+    generateObjectId() {
+        if (window.crypto && crypto.randomUUID) {
+            return crypto.randomUUID();
+        }
+        return Date.now().toString(36) + '-' + Math.random().toString(36).slice(2, 12);
+    }
+
+    findObjectIndex(id) {
+        return this.objects.findIndex(obj => obj.id === id);
+    }
+
+    translateObject(obj, dx, dy) {
+        obj.x += dx;
+        obj.y += dy;
+        if (obj.points) {
+            obj.points = obj.points.map(p => ({ x: p.x + dx, y: p.y + dy }));
+        }
+    }
+
+    // Mirrors the server: move first, then absolute values
+    applyObjectUpdate(obj, data) {
+        if (data.dx || data.dy) {
+            this.translateObject(obj, data.dx || 0, data.dy || 0);
+        }
+        ['x', 'y', 'width', 'height', 'color', 'thickness', 'text'].forEach(key => {
+            if (data[key] !== undefined) {
+                obj[key] = data[key];
+            }
+        });
+    }
+
+    sendObjectUpdate(obj, changes) {
+        this.sendDrawingEvent({
+            type: 'objectUpdate',
+            tool: 'select',
+            data: { id: obj.id, ...changes }
+        });
+    }
+
     init() {
         this.resizeCanvas();
         window.addEventListener('resize', () => this.resizeCanvas());
@@ -176,7 +526,7 @@ class Whiteboard {
         this.canvas.addEventListener('mouseup', () => this.handleMouseUp());
         this.canvas.addEventListener('mouseout', () => this.handleMouseUp());
         
-        document.querySelectorAll('.tool').forEach(btn => {
+        document.querySelectorAll('.tool[data-tool]').forEach(btn => {
             btn.addEventListener('click', (e) => {
                 this.setTool(e.target.dataset.tool);
             });
@@ -185,11 +535,41 @@ class Whiteboard {
         document.querySelector('.color-picker').addEventListener('input', (e) => {
             this.currentColor = e.target.value;
         });
+
+        // Restyle the selected object once the user settles on a value
+        document.querySelector('.color-picker').addEventListener('change', (e) => {
+            if (this.selectedObject && this.currentTool === 'select') {
+                this.selectedObject.color = e.target.value;
+                this.sendObjectUpdate(this.selectedObject, { color: e.target.value });
+                this.redraw();
+            }
+        });
         
         document.getElementById('thickness').addEventListener('input', (e) => {
             this.currentThickness = e.target.value;
             document.getElementById('thickness-value').textContent = `${e.target.value}px`;
         });
+
+        document.getElementById('thickness').addEventListener('change', (e) => {
+            if (this.selectedObject && this.currentTool === 'select') {
+                const thickness = Number(e.target.value);
+                this.selectedObject.thickness = thickness;
+                this.sendObjectUpdate(this.selectedObject, { thickness });
+                this.redraw();
+            }
+        });
+
+        // Edit the text of a selected text object
+        this.canvas.addEventListener('dblclick', () => {
+            if (this.selectedObject && this.selectedObject.type === 'text' && this.currentTool === 'select') {
+                const text = prompt('Edit text:', this.selectedObject.text);
+                if (text && text !== this.selectedObject.text) {
+                    this.selectedObject.text = text;
+                    this.sendObjectUpdate(this.selectedObject, { text });
+                    this.redraw();
+                }
+            }
+        });
         
         document.getElementById('eraser-size').addEventListener('input', (e) => {
             this.eraserSize = e.target.value;
@@ -202,8 +582,22 @@ class Whiteboard {
             }
         });
 
+        document.getElementById('undo').addEventListener('click', () => this.undo());
+        document.getElementById('redo').addEventListener('click', () => this.redo());
+
         // Add text input handling
         document.addEventListener('keydown', (e) => {
+            if (this.readOnly) return;
+            if ((e.ctrlKey || e.metaKey) && e.key.toLowerCase() === 'z') {
+                e.preventDefault();
+                e.shiftKey ? this.redo() : this.undo();
+                return;
+            }
+            if ((e.ctrlKey || e.metaKey) && e.key.toLowerCase() === 'y') {
+                e.preventDefault();
+                this.redo();
+                return;
+            }
             if (e.key === 'Delete' || e.key === 'Backspace') {
                 if (this.selectedObject && this.currentTool === 'select') {
                     this.deleteSelectedObject();
@@ -273,6 +667,8 @@ class Whiteboard {
     }
 
     handleMouseDown(e) {
+        if (this.readOnly) return;
+
         console.log('Mouse down event:', {
             tool: this.currentTool,
             x: this.startX,
@@ -290,6 +686,7 @@ class Whiteboard {
         } else {
             this.isDrawing = true;
             this.currentObject = {
+                id: this.generateObjectId(),
                 type: this.currentTool,
                 x: this.startX,
                 y: this.startY,
@@ -299,10 +696,16 @@ class Whiteboard {
                 thickness: this.currentTool === 'erase' ? this.eraserSize : this.currentThickness,
                 points: this.currentTool === 'draw' || this.currentTool === 'erase' ? [{ x: this.startX, y: this.startY }] : []
             };
+            if (this.currentTool === 'draw' || this.currentTool === 'erase') {
+                this.beginStroke();
+            }
         }
     }
 
     handleMouseMove(e) {
+        const pointer = this.canvas.getBoundingClientRect();
+        this.sendCursor(e.clientX - pointer.left, e.clientY - pointer.top);
+
         if (!this.isDrawing && !this.isResizing && this.currentTool !== 'select') return;
         
         const rect = this.canvas.getBoundingClientRect();
@@ -313,10 +716,21 @@ class Whiteboard {
             this.resizeObject(currentX, currentY);
             return;
         }
+
+        if (this.isMoving && this.selectedObject) {
+            const dx = currentX - this.startX - this.moveDX;
+            const dy = currentY - this.startY - this.moveDY;
+            this.translateObject(this.selectedObject, dx, dy);
+            this.moveDX += dx;
+            this.moveDY += dy;
+            this.redraw();
+            return;
+        }
         
         if (this.isDrawing) {
             if (this.currentTool === 'draw' || this.currentTool === 'erase') {
                 this.currentObject.points.push({ x: currentX, y: currentY });
+                this.streamStroke('strokeAppend');
                 this.redraw();
                 this.drawObject(this.currentObject);
             } else {
@@ -336,31 +750,39 @@ class Whiteboard {
     handleMouseUp() {
         console.log('Mouse up event triggered');
         
+        if (this.isMoving) {
+            if (this.selectedObject && (this.moveDX !== 0 || this.moveDY !== 0)) {
+                this.sendObjectUpdate(this.selectedObject, { dx: this.moveDX, dy: this.moveDY });
+            }
+            this.isMoving = false;
+            this.moveDX = 0;
+            this.moveDY = 0;
+            return;
+        }
+
+        if (this.isResizing && this.selectedObject) {
+            const { x, y, width, height } = this.selectedObject;
+            this.sendObjectUpdate(this.selectedObject, { x, y, width, height });
+        }
+
         if (!this.isDrawing && !this.isResizing) {
             console.log('No drawing or resizing in progress');
             return;
         }
         
         if (this.isDrawing) {
-            if ((this.currentTool === 'draw' || this.currentTool === 'erase') && this.currentObject.points.length > 1) {
-                console.log('Freehand drawing completed:', {
-                    tool: this.currentTool,
-                    pointsCount: this.currentObject.points.length,
-                    color: this.currentObject.color,
-                    thickness: this.currentObject.thickness
-                });
-                this.objects.push(this.currentObject);
-                
-                // Send drawing event through WebSocket
-                this.sendDrawingEvent({
-                    type: 'freehandDraw',
-                    tool: this.currentTool,
-                    data: {
+            if (this.currentTool === 'draw' || this.currentTool === 'erase') {
+                // The server stores the streamed points as one freehandDraw event
+                this.streamStroke('strokeEnd');
+                if (this.currentObject.points.length > 1) {
+                    console.log('Freehand drawing completed:', {
+                        tool: this.currentTool,
+                        pointsCount: this.currentObject.points.length,
                         color: this.currentObject.color,
-                        thickness: this.currentObject.thickness,
-                        points: this.currentObject.points
-                    }
-                });
+                        thickness: this.currentObject.thickness
+                    });
+                    this.objects.push(this.currentObject);
+                }
             } else if (this.currentTool !== 'draw' && this.currentTool !== 'erase') {
                 // Adjust negative dimensions
                 if (this.currentObject.width < 0) {
@@ -390,6 +812,7 @@ class Whiteboard {
                         type: 'shapeCreate',
                         tool: this.currentTool,
                         data: {
+                            id: this.currentObject.id,
                             color: this.currentObject.color,
                             thickness: this.currentObject.thickness,
                             x: this.currentObject.x,
@@ -475,6 +898,9 @@ class Whiteboard {
         this.objects.forEach(obj => {
             this.drawObject(obj);
         });
+
+        // Then the strokes others are drawing right now
+        this.liveStrokes.forEach(stroke => this.drawObject(stroke));
         
         // Draw selection handles if an object is selected
         if (this.selectedObject) {
@@ -497,6 +923,10 @@ class Whiteboard {
         
         if (clickedObject) {
             this.selectObject(clickedObject);
+            // Dragging a selected object moves it
+            this.isMoving = true;
+            this.moveDX = 0;
+            this.moveDY = 0;
         } else {
             this.deselectObject();
         }
@@ -692,6 +1122,7 @@ class Whiteboard {
         const text = prompt('Enter text:', 'Text');
         if (text) {
             const textObj = {
+                id: this.generateObjectId(),
                 type: 'text',
                 x: x,
                 y: y,
@@ -711,6 +1142,7 @@ class Whiteboard {
                 type: 'textAdd',
                 tool: 'text',
                 data: {
+                    id: textObj.id,
                     color: textObj.color,
                     thickness: textObj.thickness,
                     x: textObj.x,
@@ -729,7 +1161,7 @@ class Whiteboard {
             if (index > -1) {
                 console.log('Deleting object:', {
                     type: this.selectedObject.type,
-                    index: index
+                    id: this.selectedObject.id
                 });
                 
                 // Send object deletion event through WebSocket
@@ -737,7 +1169,7 @@ class Whiteboard {
                     type: 'objectDelete',
                     tool: 'select',
                     data: {
-                        index: index,
+                        id: this.selectedObject.id,
                         objectType: this.selectedObject.type
                     }
                 });
@@ -753,6 +1185,17 @@ class Whiteboard {
         }
     }
 
+    // Undo and redo are resolved by the server for our own operations only
+    undo() {
+        this.deselectObject();
+        this.sendDrawingEvent({ type: 'undo' });
+    }
+
+    redo() {
+        this.deselectObject();
+        this.sendDrawingEvent({ type: 'redo' });
+    }
+
     clearBoard() {
         console.log('Clear board requested. Current object count:', this.objects.length);
         
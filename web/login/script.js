@@ -14,21 +14,51 @@ const signUpEmailError = document.getElementById('signUpEmailError');
 const signUpPasswordError = document.getElementById('signUpPasswordError');
 
 document.addEventListener("DOMContentLoaded", function () {
+    if (takeSSOResult()) return;
     checkAuthAndSkip()
+    showSSO()
 });
 
+// single sign-on comes back to this page with the auth token or an error in the fragment
+function takeSSOResult(){
+    const fragment = new URLSearchParams(window.location.hash.slice(1));
+    if (!fragment.has('auth-token') && !fragment.has('sso-error')) return false;
+    history.replaceState(null, '', window.location.pathname);
+
+    if (fragment.has('sso-error')) {
+        setMessage('formMessage', 'Single sign-on failed: ' + fragment.get('sso-error'));
+        return false;
+    }
+
+    localStorage.setItem('auth-token', fragment.get('auth-token'));
+    localStorage.setItem('token-expiry', parseInt(fragment.get('auth-expiry-at'), 10) * 1000);
+    window.location.href = '/dashboard';
+    return true;
+}
+
+async function showSSO(){
+    try{
+        const res = await fetch('/auth/oidc');
+        if (!res.ok) return;
+        const data = await res.json();
+        document.getElementById('ssoSignIn').hidden = !data.enabled;
+    }catch(error){
+        console.error("Single sign-on check failed:", error);
+    }
+}
+
 async function checkAuthAndSkip(){
     const token = localStorage.getItem("auth-token")
     const expiry = parseInt(localStorage.getItem("token-expiry"), 10);
 
     if(token && expiry && Date.now() < expiry){
-        window.location.href = "/drawboard"
+        window.location.href = "/dashboard"
         return;
     }
 
     const refreshed = await this.refreshAccessToken();
     if (refreshed) {
-        window.location.href = "/drawboard";
+        window.location.href = "/dashboard";
     }
 }
 
@@ -119,7 +149,7 @@ signInForm.addEventListener('submit', async (e) => {
                 localStorage.setItem('token-expiry', Date.now() + 15 * 60 * 1000)
                 // Set success message
                 setMessage('formMessage', 'Login successful! Redirecting...');
-                window.location.href = '/drawboard'; // Redirect to the main app
+                window.location.href = '/dashboard'; // Redirect to the main app
             } else {
                 // Set error message
                 const errorText = await response.text();